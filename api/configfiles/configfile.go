@@ -1,11 +1,15 @@
 package configfiles
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
+	"github.com/go-resty/resty/v2"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/nxsre/polaris-go"
+	"github.com/nxsre/polaris-go/crypto"
 	"github.com/nxsre/polaris-go/sdk"
-	"log"
+	"github.com/polarismesh/specification/source/go/api/v1/model"
 )
 
 type ConfigFile struct {
@@ -21,36 +25,259 @@ type ConfigFile struct {
 	Tags               []sdk.ConfigFileTag `json:"tags"`
 }
 
-func CreateAndPub(config *ConfigFile) (*ConfigFileResult, error) {
+// Client 绑定北极星客户端的配置文件管理接口
+type Client struct {
+	polarisClient *polaris.Polaris
+}
+
+// NewClient 创建使用 client 访问北极星的配置文件管理接口
+func NewClient(client *polaris.Polaris) *Client {
+	return &Client{polarisClient: client}
+}
+
+// PublishOption 发布配置文件的选项
+type PublishOption func(*publishOptions)
+
+type publishOptions struct {
+	encrypt  bool
+	algo     string
+	dataKey  []byte
+	provider crypto.KeyProvider
+}
+
+// WithEncryption 发布前在客户端加密配置文件内容，algo 为 crypto 中注册的加密算法，为空时使用与北极星控制台
+// 及其他 SDK 兼容的 AES，同时使用 WithKeyProvider 时为 AES-GCM，dataKey 为空时由加密算法生成数据密钥。
+// 加密后设置 internal-encrypted、internal-datakey、internal-encryptalgo tag，覆盖 ConfigFile 中同名的 tag，
+// 不修改传入的 ConfigFile
+func WithEncryption(algo string, dataKey []byte) PublishOption {
+	return func(o *publishOptions) {
+		o.encrypt, o.algo, o.dataKey = true, algo, dataKey
+	}
+}
+
+// WithKeyProvider 发布前在客户端加密配置文件内容，数据密钥使用 provider 包装后保存在
+// internal-wrapped-datakey tag，并在 internal-keyprovider tag 记录提供者名称，不设置 internal-datakey。
+// 未使用 WithEncryption 指定数据密钥时，ConfigFile 已带有包装后的数据密钥的，解包后继续使用该数据密钥。
+// 获取配置文件的一方需要通过 crypto.RegisterKeyProvider 注册同名的提供者
+func WithKeyProvider(provider crypto.KeyProvider) PublishOption {
+	return func(o *publishOptions) {
+		o.encrypt, o.provider = true, provider
+	}
+}
+
+// CreateAndPub 创建并发布配置文件，返回码非成功时返回 *polaris.PolarisError
+func (c *Client) CreateAndPub(config *ConfigFile, opts ...PublishOption) (*ConfigFileResult, error) {
+	return c.CreateAndPubCtx(context.Background(), config, opts...)
+}
+
+// CreateAndPubCtx 创建并发布配置文件，ctx 控制本次请求的超时及取消
+func (c *Client) CreateAndPubCtx(ctx context.Context, config *ConfigFile, opts ...PublishOption) (result *ConfigFileResult, err error) {
+	ctx, span := c.polarisClient.StartSpan(ctx, "polaris.CreateAndPubConfigFile",
+		polaris.ConfigFileAttributes(config.Namespace, config.Group, config.FileName)...)
+	var resp *resty.Response
+	defer func() { polaris.EndSpan(span, resp, result, err) }()
+
+	options := &publishOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.encrypt {
+		if config, err = encryptConfigFile(ctx, config, options); err != nil {
+			return nil, err
+		}
+	}
+
 	body, err := jsoniter.Marshal(config)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := polaris.DefaultClient.Resty().R().
+	resp, err = c.polarisClient.Resty().R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetBody(body).
-		Post(sdk.PolarisUrl("/config/v1/configfiles/createandpub"))
+		Post(c.polarisClient.URL("/config/v1/configfiles/createandpub"))
 	if err != nil {
 		return nil, err
 	}
-	result := ConfigFileResult{}
-	if err := jsoniter.Unmarshal(resp.Body(), &result); err != nil {
+	result = &ConfigFileResult{}
+	if err := polaris.ParseResponse(resp, result); err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return result, nil
 }
 
-func Delete(ns, group, fileName string) {
+// encryptConfigFile 返回内容加密后的配置文件副本
+func encryptConfigFile(ctx context.Context, config *ConfigFile, options *publishOptions) (*ConfigFile, error) {
+	algo, dataKey := options.algo, options.dataKey
+	if algo == "" {
+		// 包装后的数据密钥只有注册了密钥提供者的一方能够解包，无需与北极星控制台及其他 SDK 兼容
+		algo = crypto.AlgoAES
+		if options.provider != nil {
+			algo = crypto.AlgoAESGCM
+		}
+	}
+	cryptor, err := crypto.GetCryptor(algo)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKey) == 0 && options.provider != nil {
+		if dataKey, err = unwrapDataKey(ctx, config, options.provider); err != nil {
+			return nil, err
+		}
+	}
+	if len(dataKey) == 0 {
+		if dataKey, err = cryptor.GenerateKey(); err != nil {
+			return nil, err
+		}
+	}
+	content, err := cryptor.Encrypt(config.Content, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyTags := []sdk.ConfigFileTag{{Key: sdk.ConfigFileTagKeyDataKey, Value: base64.StdEncoding.EncodeToString(dataKey)}}
+	if options.provider != nil {
+		wrapped, err := options.provider.WrapKey(ctx, dataKey)
+		if err != nil {
+			return nil, err
+		}
+		keyTags = []sdk.ConfigFileTag{
+			{Key: sdk.ConfigFileTagKeyWrappedDataKey, Value: base64.StdEncoding.EncodeToString(wrapped)},
+			{Key: sdk.ConfigFileTagKeyKeyProvider, Value: options.provider.Name()},
+		}
+	}
+
+	encrypted := *config
+	encrypted.Content = content
+	encrypted.Tags = append(withoutEncryptionTags(config.Tags),
+		sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyUseEncrypted, Value: "true"},
+		sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyEncryptAlgo, Value: algo},
+	)
+	encrypted.Tags = append(encrypted.Tags, keyTags...)
+	return &encrypted, nil
+}
+
+// unwrapDataKey 解包配置文件中已有的数据密钥，没有包装后的数据密钥时返回 nil
+func unwrapDataKey(ctx context.Context, config *ConfigFile, provider crypto.KeyProvider) ([]byte, error) {
+	var wrapped, name string
+	for _, tag := range config.Tags {
+		switch tag.Key {
+		case sdk.ConfigFileTagKeyWrappedDataKey:
+			wrapped = tag.Value
+		case sdk.ConfigFileTagKeyKeyProvider:
+			name = tag.Value
+		}
+	}
+	if wrapped == "" {
+		return nil, nil
+	}
+	if name != provider.Name() {
+		return nil, fmt.Errorf("data key is wrapped by key provider %q, not %q", name, provider.Name())
+	}
+	key, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return provider.UnwrapKey(ctx, key)
+}
+
+// withoutEncryptionTags 返回去掉加密相关 tag 的副本
+func withoutEncryptionTags(tags []sdk.ConfigFileTag) []sdk.ConfigFileTag {
+	result := make([]sdk.ConfigFileTag, 0, len(tags)+4)
+	for _, tag := range tags {
+		switch tag.Key {
+		case sdk.ConfigFileTagKeyUseEncrypted, sdk.ConfigFileTagKeyDataKey, sdk.ConfigFileTagKeyEncryptAlgo,
+			sdk.ConfigFileTagKeyWrappedDataKey, sdk.ConfigFileTagKeyKeyProvider:
+		default:
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// Rewrap 使用密钥提供者当前的主密钥重新包装已发布配置文件的数据密钥并重新发布，
+// 返回码非成功时返回 *polaris.PolarisError
+func (c *Client) Rewrap(file *sdk.ConfigFile, releaseName string) (*ConfigFileResult, error) {
+	return c.RewrapCtx(context.Background(), file, releaseName)
+}
+
+// RewrapCtx 使用密钥提供者当前的主密钥重新包装已发布配置文件的数据密钥并重新发布，用于主密钥轮换。
+// file 为 sdk 获取的配置文件，内容密文及数据密钥不变，提供者按 internal-keyprovider tag 从
+// crypto.RegisterKeyProvider 注册的提供者中选择，ctx 控制解包、包装及发布请求的超时及取消
+func (c *Client) RewrapCtx(ctx context.Context, file *sdk.ConfigFile, releaseName string) (*ConfigFileResult, error) {
+	wrapped := file.GetWrappedDataKey()
+	if wrapped == "" {
+		return nil, fmt.Errorf("config file %s/%s/%s has no wrapped data key", file.GetNamespace(), file.GetFileGroup(), file.GetFileName())
+	}
+	provider, err := crypto.GetKeyProvider(file.GetKeyProvider())
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	rewrapped, err := crypto.RewrapKey(ctx, provider, key)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]sdk.ConfigFileTag, 0, len(file.Tags))
+	for _, tag := range file.Tags {
+		if tag.Key == sdk.ConfigFileTagKeyWrappedDataKey {
+			tag.Value = base64.StdEncoding.EncodeToString(rewrapped)
+		}
+		tags = append(tags, tag)
+	}
+	return c.CreateAndPubCtx(ctx, &ConfigFile{
+		ReleaseName: releaseName,
+		Format:      file.Format,
+		FileName:    file.GetFileName(),
+		Namespace:   file.GetNamespace(),
+		Group:       file.GetFileGroup(),
+		Content:     file.GetSourceContent(),
+		Tags:        tags,
+	})
+}
+
+// Delete 删除配置文件，返回码非成功时返回 *polaris.PolarisError
+func (c *Client) Delete(ns, group, fileName string) error {
+	return c.DeleteCtx(context.Background(), ns, group, fileName)
+}
+
+// DeleteCtx 删除配置文件，ctx 控制本次请求的超时及取消
+func (c *Client) DeleteCtx(ctx context.Context, ns, group, fileName string) (err error) {
+	ctx, span := c.polarisClient.StartSpan(ctx, "polaris.DeleteConfigFile", polaris.ConfigFileAttributes(ns, group, fileName)...)
+	var resp *resty.Response
+	result := &ConfigFileResult{}
+	defer func() { polaris.EndSpan(span, resp, result, err) }()
+
 	fileUri := fmt.Sprintf("/config/v1/configfiles")
-	resp, err := polaris.DefaultClient.Resty().R().
+	resp, err = c.polarisClient.Resty().R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetQueryParams(map[string]string{
 			"namespace": ns,
 			"group":     group,
 			"name":      fileName,
 		}).
-		Delete(sdk.PolarisUrl(fileUri))
-	log.Println(fileUri, resp.String(), err)
+		Delete(c.polarisClient.URL(fileUri))
+	if err != nil {
+		return err
+	}
+	return polaris.ParseResponse(resp, result)
+}
+
+// CreateAndPub 使用 polaris.DefaultClient 创建并发布配置文件
+//
+// Deprecated: 使用 NewClient 创建 Client 后调用 Client.CreateAndPub
+func CreateAndPub(config *ConfigFile, opts ...PublishOption) (*ConfigFileResult, error) {
+	return NewClient(polaris.DefaultClient).CreateAndPub(config, opts...)
+}
+
+// Delete 使用 polaris.DefaultClient 删除配置文件
+//
+// Deprecated: 使用 NewClient 创建 Client 后调用 Client.Delete
+func Delete(ns, group, fileName string) error {
+	return NewClient(polaris.DefaultClient).Delete(ns, group, fileName)
 }
 
 type ConfigFileResult struct {
@@ -62,3 +289,13 @@ type ConfigFileResult struct {
 	ConfigFileReleaseHistory any    `json:"configFileReleaseHistory"`
 	ConfigFileTemplate       any    `json:"configFileTemplate"`
 }
+
+// GetCode 获取响应体code
+func (c *ConfigFileResult) GetCode() model.Code {
+	return model.Code(c.Code)
+}
+
+// GetMessage 获取响应体信息
+func (c *ConfigFileResult) GetMessage() string {
+	return c.Info
+}
@@ -3,18 +3,24 @@ package polaris
 // 基于 https://github.com/esiqveland/balancer 实现客户端负载均衡
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"errors"
-	"fmt"
 	"github.com/esiqveland/balancer"
-	"github.com/esiqveland/balancer/httpbalancer"
 	"github.com/go-resty/resty/v2"
 	"github.com/nxsre/polaris-go/log"
-	"github.com/sirupsen/logrus"
+	"github.com/polarismesh/specification/source/go/api/v1/model"
 	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,39 +29,153 @@ var (
 	DefaultClient *Polaris
 )
 
+const (
+	// TokenHeader 鉴权 token 请求头
+	TokenHeader = "X-Polaris-Token"
+
+	loginUri     = "/core/v1/user/login"
+	watchFileUri = "/config/v1/WatchConfigFile"
+)
+
 type Polaris struct {
-	client *resty.Client
-	logger *logrus.Logger
-	token  string
+	client  *resty.Client
+	logger  log.Logger
+	metrics Metrics
+	tracer  trace.Tracer
+	baseURL string
+
+	credentials CredentialProvider
+
+	// tokenLock 保护 token，登录刷新时与并发请求互斥
+	tokenLock sync.RWMutex
+	token     string
+	// loginLock 保证同一时刻只有一个协程在重新登录
+	loginLock sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func (p *Polaris) Resty() *resty.Client {
 	return p.client
 }
 
+// URL 拼接接口的完整访问地址
+func (p *Polaris) URL(uri string) string {
+	fullUrl, _ := url.JoinPath(p.baseURL, uri)
+	return fullUrl
+}
+
+// Token 获取当前使用的鉴权 token
+func (p *Polaris) Token() string {
+	p.tokenLock.RLock()
+	defer p.tokenLock.RUnlock()
+	return p.token
+}
+
+func (p *Polaris) setToken(token string) {
+	p.tokenLock.Lock()
+	defer p.tokenLock.Unlock()
+	p.token = token
+}
+
+// Close 停止客户端的后台任务
+func (p *Polaris) Close() {
+	p.cancel()
+}
+
 func NewPolaris(servers []string, username, password string) (*Polaris, error) {
-	httpClient, err := BalancerClient(resty.New().GetClient(), servers)
-	if err != nil {
-		return nil, err
+	return NewPolarisWithOptions(servers, WithCredentials(username, password))
+}
+
+// NewPolarisWithOptions 创建北极星客户端，servers 为北极星节点地址列表，
+// 为空时直接访问 WithBaseURL 指定的地址
+func NewPolarisWithOptions(servers []string, opts ...Option) (*Polaris, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
 	}
-	client := resty.NewWithClient(httpClient)
-	client.SetLogger(log.With("app", "resty"))
 
-	resp, err := client.SetRetryCount(3).SetRetryWaitTime(3*time.Second).R().
-		EnableTrace().SetHeader("Content-Type", "application/json").
-		SetBody(fmt.Sprintf(`{"name":"%s","password":"%s"}`, username, password)).
-		Post("http://polaris.com/core/v1/user/login")
+	var httpClient *http.Client
+	if o.httpClient != nil {
+		c := *o.httpClient
+		httpClient = &c
+	} else {
+		httpClient = resty.New().GetClient()
+	}
+	if o.transport != nil {
+		httpClient.Transport = o.transport
+	}
+	if httpClient.Transport == nil {
+		httpClient.Transport = http.DefaultTransport
+	}
+	if o.tls != nil {
+		transport, ok := httpClient.Transport.(*http.Transport)
+		if !ok {
+			return nil, errors.New("polaris: tls config requires *http.Transport")
+		}
+		tlsConfig, err := o.tls.build()
+		if err != nil {
+			return nil, err
+		}
+		transport = transport.Clone()
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = transport
+	}
 
-	var token string
-	if err != nil {
-		return nil, err
+	ctx, cancel := context.WithCancel(context.Background())
+	if len(servers) > 0 {
+		transport, err := newBalancedTransport(ctx, httpClient.Transport, servers, o)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		httpClient.Transport = transport
 	} else {
-		token = gjson.GetBytes(resp.Body(), "loginResponse.token").String()
+		httpClient.Transport = &metricsTransport{metrics: o.metrics, next: httpClient.Transport}
 	}
 
 	polarisClient := &Polaris{
-		client: client.SetRetryCount(3).SetRetryWaitTime(3*time.Second).SetHeader("X-Polaris-Token", token),
-		token:  token,
+		logger:      o.logger,
+		metrics:     o.metrics,
+		tracer:      newTracer(o),
+		baseURL:     o.baseURL,
+		credentials: o.credentials,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	httpClient.Transport = &timeoutTransport{timeout: o.timeout, next: httpClient.Transport}
+	httpClient.Transport = newDebugTransport(o.debug, o.logger, httpClient.Transport)
+	// 鉴权失败时自动重新登录并重试
+	httpClient.Transport = &authTransport{polaris: polarisClient, next: httpClient.Transport}
+
+	client := resty.NewWithClient(httpClient).
+		SetLogger(o.logger.With("app", "resty")).
+		SetRetryCount(o.retryCount).
+		SetRetryWaitTime(o.retryWaitTime).
+		AddRetryHook(retryHook(o.metrics))
+	if o.retryMaxWaitTime > 0 {
+		client.SetRetryMaxWaitTime(o.retryMaxWaitTime)
+	}
+	if o.userAgent != "" {
+		client.SetHeader("User-Agent", o.userAgent)
+	}
+	if o.tracerProvider != nil {
+		client.OnBeforeRequest(injectTraceContext(propagator(o)))
+	}
+	if len(o.rateLimits) > 0 {
+		client.OnBeforeRequest(newRateLimiter(o.rateLimits, o.metrics).middleware)
+	}
+	polarisClient.client = client
+
+	if o.credentials != nil {
+		if err := polarisClient.login(ctx); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	if o.tokenRefreshInterval > 0 {
+		polarisClient.StartTokenRefresh(ctx, o.tokenRefreshInterval)
 	}
 
 	if DefaultClient == nil {
@@ -64,73 +184,305 @@ func NewPolaris(servers []string, username, password string) (*Polaris, error) {
 	return polarisClient, nil
 }
 
+// login 使用凭据登录并替换 token，凭据中带有 token 时直接使用
+func (p *Polaris) login(ctx context.Context) error {
+	if p.credentials == nil {
+		return errors.New("polaris: no credentials to login")
+	}
+	cred, err := p.credentials.Credential(ctx)
+	if err != nil {
+		return err
+	}
+	if cred.Token != "" {
+		p.setToken(cred.Token)
+		return nil
+	}
+
+	resp, err := p.client.R().SetContext(ctx).
+		EnableTrace().SetHeader("Content-Type", "application/json").
+		SetBody(map[string]string{"name": cred.Username, "password": cred.Password}).
+		Post(p.URL(loginUri))
+	if err != nil {
+		return err
+	}
+	result := gjson.ParseBytes(resp.Body())
+	code := model.Code(result.Get("code").Int())
+	if err := CheckResponse(resp, code, result.Get("info").String()); err != nil {
+		return err
+	}
+	token := result.Get("loginResponse.token").String()
+	if token == "" {
+		return NewError(resp, code, "loginResponse.token is missing in login response")
+	}
+	p.setToken(token)
+	return nil
+}
+
+// RefreshToken 重新登录获取新的 token
+func (p *Polaris) RefreshToken(ctx context.Context) error {
+	p.loginLock.Lock()
+	defer p.loginLock.Unlock()
+	return p.refresh(ctx)
+}
+
+func (p *Polaris) refresh(ctx context.Context) error {
+	err := p.login(ctx)
+	p.metrics.IncLoginRefresh(err == nil)
+	return err
+}
+
+// refreshExpiredToken 在 expired 仍为当前 token 时重新登录，
+// 多个请求同时鉴权失败时只会登录一次
+func (p *Polaris) refreshExpiredToken(ctx context.Context, expired string) error {
+	p.loginLock.Lock()
+	defer p.loginLock.Unlock()
+	if p.Token() != expired {
+		return nil
+	}
+	p.logger.Warnln("polaris token is invalid, login again")
+	return p.refresh(ctx)
+}
+
+// StartTokenRefresh 按 interval 周期性主动刷新 token，ctx 结束后停止
+func (p *Polaris) StartTokenRefresh(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := p.RefreshToken(ctx); err != nil {
+					p.logger.Errorln("refresh polaris token:", err)
+				}
+			}
+		}
+	}()
+}
+
+// authTransport 为请求注入 token，鉴权失败时重新登录并重试一次
+type authTransport struct {
+	polaris *Polaris
+	next    http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, loginUri) {
+		return t.next.RoundTrip(req)
+	}
+
+	token := t.polaris.Token()
+	resp, err := t.next.RoundTrip(withToken(req, token))
+	// 没有凭据时无法重新登录
+	if err != nil || t.polaris.credentials == nil || !isAuthFailure(resp) {
+		return resp, err
+	}
+	// 请求体无法重放时不重试
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	if err := t.polaris.refreshExpiredToken(req.Context(), token); err != nil {
+		t.polaris.logger.Errorln("login polaris:", err)
+		return resp, nil
+	}
+
+	retry := withToken(req, t.polaris.Token())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+	t.polaris.metrics.IncRetry(req.URL.Path)
+	return t.next.RoundTrip(retry)
+}
+
+// isLongPoll 判断是否为配置文件监听的长轮询请求
+func isLongPoll(req *http.Request) bool {
+	return strings.HasSuffix(req.URL.Path, watchFileUri)
+}
+
+func withToken(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	if token != "" {
+		r.Header.Set(TokenHeader, token)
+	}
+	return r
+}
+
+// isAuthFailure 判断响应是否为 token 失效，只有北极星 token 相关的错误码才需要重新登录，
+// NotAllowedAccess 等权限不足的错误重新登录也无法恢复
+func isAuthFailure(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusBadRequest, http.StatusForbidden:
+	default:
+		return false
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return isAuthCode(model.Code(gjson.GetBytes(body, "code").Int()))
+}
+
+func isAuthCode(code model.Code) bool {
+	switch code {
+	case model.Code_Unauthorized,
+		model.Code_InvalidUserToken,
+		model.Code_EmptyAutToken,
+		model.Code_TokenDisabled,
+		model.Code_TokenNotExisted:
+		return true
+	}
+	return false
+}
+
+// BalancerClient 将 client 包装为按权重轮询访问 addrs 中北极星节点的客户端，
+// 节点地址支持 http 和 https，主机名会被解析为 IP 分别作为节点
 func BalancerClient(client *http.Client, addrs []string) (*http.Client, error) {
-	balance := WeightRoundRobinBalance{}
+	transport, err := newBalancedTransport(context.Background(), client.Transport, addrs, defaultOptions())
+	if err != nil {
+		return nil, err
+	}
+	client.Transport = transport
+	return client, nil
+}
+
+func newBalancedTransport(ctx context.Context, base http.RoundTripper, addrs []string, o *options) (*balancedTransport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	balance := &WeightRoundRobinBalance{base: base, health: o.health.withDefaults(), strategy: o.strategy, metrics: o.metrics}
 	for _, addr := range addrs {
 		u, err := url.Parse(addr)
 		if err != nil {
 			log.Errorln(err)
 			continue
 		}
-		port, err := strconv.Atoi(u.Port())
-		if err != nil {
-			log.Errorln(err)
-			continue
+		scheme := u.Scheme
+		if scheme == "" {
+			scheme = "http"
 		}
-		if ip := net.ParseIP(u.Hostname()); ip == nil {
-			ips, err := net.LookupIP(u.Hostname())
+		port := defaultPort(scheme)
+		if u.Port() != "" {
+			port, err = strconv.Atoi(u.Port())
 			if err != nil {
-				return nil, err
+				log.Errorln(err)
+				continue
 			}
-			for _, ip := range ips {
-				balance.Add(WeightHost{host: balancer.Host{Address: ip, Port: port}, weight: 1})
+		}
+		// 节点权重通过地址参数指定，如 http://polaris1:8090?weight=3
+		weight := 1
+		if w := u.Query().Get("weight"); w != "" {
+			weight, err = strconv.Atoi(w)
+			if err != nil || weight <= 0 {
+				log.Errorf("invalid weight of polaris server %s", addr)
+				continue
 			}
-		} else {
-			balance.Add(WeightHost{host: balancer.Host{Address: ip, Port: port}, weight: 1})
 		}
-
+		server := WeightHost{scheme: scheme, hostname: u.Hostname(), weight: weight}
+		server.host.Port = port
+		ips, err := server.lookup()
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			host := server
+			host.host.Address = ip
+			balance.Add(host)
+		}
+		balance.servers = append(balance.servers, server)
 	}
 
-	return httpbalancer.Wrap(client, &balance), nil
+	if balance.health.ProbeInterval > 0 {
+		go balance.probe(ctx)
+	}
+	if o.dnsRefreshInterval > 0 {
+		go balance.refresh(ctx, o.dnsRefreshInterval)
+	}
+	return &balancedTransport{balance: balance}, nil
 }
 
-type WeightHost struct {
-	host   balancer.Host
-	weight int
+func defaultPort(scheme string) int {
+	if scheme == "https" {
+		return 443
+	}
+	return 80
 }
 
-type WeightRoundRobinBalance struct {
-	curIndex int
-	rss      []*WeightNode
-	rsw      []int
+// nodeTransport 为节点创建独立的 transport，https 节点使用原始主机名作为 SNI 并校验证书
+func nodeTransport(base http.RoundTripper, node *WeightNode) http.RoundTripper {
+	t, ok := base.(*http.Transport)
+	if !ok || node.scheme != "https" {
+		return base
+	}
+	t = t.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	if t.TLSClientConfig.ServerName == "" {
+		t.TLSClientConfig.ServerName = node.hostname
+	}
+	return t
 }
 
-type WeightNode struct {
-	host            balancer.Host
-	Weight          int //初始化时对节点约定的权重
-	currentWeight   int //节点临时权重，每轮都会变化
-	effectiveWeight int //有效权重, 默认与weight相同 , totalWeight = sum(effectiveWeight)  //出现故障就-1
+// balancedTransport 选择北极星节点并将请求转发到该节点
+type balancedTransport struct {
+	balance *WeightRoundRobinBalance
 }
 
-//1, currentWeight = currentWeight + effectiveWeight
-//2, 选中最大的currentWeight节点为选中节点
-//3, currentWeight = currentWeight - totalWeight
+func (t *balancedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	node, err := t.balance.nextNode()
+	if err != nil {
+		return nil, err
+	}
 
-func (r *WeightRoundRobinBalance) Add(host WeightHost) error {
-	node := &WeightNode{
-		host:   host.host,
-		Weight: host.weight,
+	// RoundTripper 不能修改传入的请求
+	r := req.Clone(req.Context())
+	r.URL.Scheme = node.scheme
+	r.URL.Host = node.addr()
+	r.Host = node.authority()
+	start := time.Now()
+	resp, err := node.transport.RoundTrip(r)
+	latency := time.Since(start)
+	// 长轮询请求的耗时不代表节点延迟
+	if isLongPoll(req) || err != nil {
+		latency = 0
 	}
-	node.effectiveWeight = node.Weight
-	r.rss = append(r.rss, node)
-	return nil
+	t.balance.release(node, latency)
+	if !isCallerCanceled(req, err) {
+		t.balance.report(node, !isNodeFailure(resp, err))
+	}
+	return resp, err
 }
 
-func (r *WeightRoundRobinBalance) Next() (balancer.Host, error) {
+// Strategy 北极星节点选择策略，Pick 调用时已持有负载均衡器的锁，nodes 不为空且不含被摘除的节点
+type Strategy interface {
+	Pick(nodes []*WeightNode) *WeightNode
+}
+
+// ewmaAlpha 延迟移动平均中最新样本的权重
+const ewmaAlpha = 0.3
+
+// WeightRoundRobinStrategy 平滑加权轮询，默认策略
+//
+// 1, currentWeight = currentWeight + effectiveWeight
+// 2, 选中最大的currentWeight节点为选中节点
+// 3, currentWeight = currentWeight - totalWeight
+type WeightRoundRobinStrategy struct{}
+
+func (WeightRoundRobinStrategy) Pick(nodes []*WeightNode) *WeightNode {
 	var best *WeightNode
 	total := 0
-	for i := 0; i < len(r.rss); i++ {
-		w := r.rss[i]
+	for i := 0; i < len(nodes); i++ {
+		w := nodes[i]
 		//1 计算所有有效权重
 		total += w.effectiveWeight
 		//2 修改当前节点临时权重
@@ -146,17 +498,291 @@ func (r *WeightRoundRobinBalance) Next() (balancer.Host, error) {
 		}
 	}
 
-	if best == nil {
-		return balancer.Host{}, errors.New("best is nil")
-	}
 	//5 变更临时权重为 临时权重-有效权重之和
 	best.currentWeight -= total
-	return best.host, nil
+	return best
+}
+
+// LeastOutstandingStrategy 选择按权重折算后进行中请求最少的节点
+type LeastOutstandingStrategy struct{}
+
+func (LeastOutstandingStrategy) Pick(nodes []*WeightNode) *WeightNode {
+	var best *WeightNode
+	for _, node := range nodes {
+		if best == nil || outstandingScore(node) < outstandingScore(best) {
+			best = node
+		}
+	}
+	return best
+}
+
+func outstandingScore(node *WeightNode) float64 {
+	return float64(node.outstanding+1) / float64(node.Weight)
+}
+
+// P2CStrategy power of two choices，随机选择两个节点，取进行中请求较少的一个
+type P2CStrategy struct{}
+
+func (P2CStrategy) Pick(nodes []*WeightNode) *WeightNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	i := rand.Intn(len(nodes))
+	j := rand.Intn(len(nodes) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := nodes[i], nodes[j]
+	if outstandingScore(b) < outstandingScore(a) {
+		return b
+	}
+	return a
+}
+
+// LatencyEWMAStrategy 选择延迟移动平均值与进行中请求数乘积最小的节点，
+// 尚无延迟数据的节点优先选择
+type LatencyEWMAStrategy struct{}
+
+func (LatencyEWMAStrategy) Pick(nodes []*WeightNode) *WeightNode {
+	var best *WeightNode
+	for _, node := range nodes {
+		if node.latency == 0 && node.outstanding == 0 {
+			return node
+		}
+		if best == nil || latencyScore(node) < latencyScore(best) {
+			best = node
+		}
+	}
+	return best
+}
+
+func latencyScore(node *WeightNode) float64 {
+	return float64(node.latency) * outstandingScore(node)
+}
+
+type WeightHost struct {
+	host     balancer.Host
+	scheme   string
+	hostname string
+	weight   int
+}
+
+// lookup 解析主机名对应的 IP，主机名本身为 IP 时直接返回
+func (h WeightHost) lookup() ([]net.IP, error) {
+	if ip := net.ParseIP(h.hostname); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	return net.LookupIP(h.hostname)
+}
+
+type WeightRoundRobinBalance struct {
+	curIndex int
+	rss      []*WeightNode
+	rsw      []int
+
+	lock     sync.Mutex
+	health   HealthCheckConfig
+	strategy Strategy
+	// updateLock 保证同一时刻只有一个 Update 在执行
+	updateLock sync.Mutex
+
+	// servers 配置的北极星节点地址，Update 时重新解析
+	servers []WeightHost
+	// base 创建节点 transport 使用的基础 transport
+	base    http.RoundTripper
+	metrics Metrics
+}
+
+type WeightNode struct {
+	host            balancer.Host
+	scheme          string            // 节点协议，http 或 https
+	hostname        string            // 节点地址中的原始主机名，用于 Host 请求头和 TLS SNI
+	transport       http.RoundTripper // 访问该节点使用的 transport
+	Weight          int               //初始化时对节点约定的权重
+	currentWeight   int               //节点临时权重，每轮都会变化
+	effectiveWeight int               //有效权重, 默认与weight相同 , totalWeight = sum(effectiveWeight)  //出现故障就-1
+	failures        int               //连续失败次数
+	ejectedUntil    time.Time         //节点被摘除的截止时间
+	outstanding     int               //进行中的请求数
+	latency         time.Duration     //请求延迟的指数加权移动平均值
+}
+
+func (r *WeightRoundRobinBalance) Add(host WeightHost) error {
+	node := r.newNode(host)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.rss = append(r.rss, node)
+	return nil
+}
+
+func (r *WeightRoundRobinBalance) newNode(host WeightHost) *WeightNode {
+	node := &WeightNode{
+		host:     host.host,
+		scheme:   host.scheme,
+		hostname: host.hostname,
+		Weight:   host.weight,
+	}
+	node.effectiveWeight = node.Weight
+	if r.base != nil {
+		node.transport = nodeTransport(r.base, node)
+		if r.metrics != nil {
+			node.transport = &metricsTransport{metrics: r.metrics, next: node.transport}
+		}
+	}
+	return node
+}
+
+// key 节点的唯一标识
+func (h WeightHost) key() string {
+	return h.scheme + "://" + h.host.String()
+}
+
+func (n *WeightNode) key() string {
+	return n.scheme + "://" + n.host.String()
+}
+
+// addr 节点的 IP 和端口
+func (n *WeightNode) addr() string {
+	return net.JoinHostPort(n.host.Address.String(), strconv.Itoa(n.host.Port))
+}
+
+// authority 节点的原始主机名和端口
+func (n *WeightNode) authority() string {
+	return net.JoinHostPort(n.hostname, strconv.Itoa(n.host.Port))
+}
+
+func (r *WeightRoundRobinBalance) Next() (balancer.Host, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	node := r.pick()
+	if node == nil {
+		return balancer.Host{}, balancer.ErrNoHosts
+	}
+	return node.host, nil
+}
+
+// nextNode 选择节点并记录为进行中的请求，请求结束后需调用 release
+func (r *WeightRoundRobinBalance) nextNode() (*WeightNode, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	node := r.pick()
+	if node == nil {
+		return nil, balancer.ErrNoHosts
+	}
+	node.outstanding++
+	return node, nil
+}
+
+// release 请求结束，latency 为 0 时不计入延迟统计
+func (r *WeightRoundRobinBalance) release(node *WeightNode, latency time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	node.outstanding--
+	if latency > 0 {
+		if node.latency == 0 {
+			node.latency = latency
+		} else {
+			node.latency = time.Duration(ewmaAlpha*float64(latency) + (1-ewmaAlpha)*float64(node.latency))
+		}
+	}
+}
+
+// pick 使用选择策略选择节点，调用方需持有锁
+func (r *WeightRoundRobinBalance) pick() *WeightNode {
+	// 跳过被摘除的节点，全部被摘除时仍从所有节点中选择
+	nodes := make([]*WeightNode, 0, len(r.rss))
+	now := time.Now()
+	for _, node := range r.rss {
+		if !now.Before(node.ejectedUntil) {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		nodes = r.rss
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	strategy := r.strategy
+	if strategy == nil {
+		strategy = WeightRoundRobinStrategy{}
+	}
+	return strategy.Pick(nodes)
 }
 
 func (r *WeightRoundRobinBalance) Get() (balancer.Host, error) {
 	return r.Next()
 }
 
+// Update 重新解析北极星节点的主机名，增加新解析到的节点并移除已不存在的节点，
+// 存活节点保留原有的权重及健康状态，主机名解析失败时保留其原有节点
 func (r *WeightRoundRobinBalance) Update() {
+	r.updateLock.Lock()
+	defer r.updateLock.Unlock()
+
+	r.lock.Lock()
+	current := make(map[string]*WeightNode, len(r.rss))
+	for _, node := range r.rss {
+		current[node.key()] = node
+	}
+	r.lock.Unlock()
+
+	var nodes []*WeightNode
+	seen := map[string]bool{}
+	keep := func(node *WeightNode) {
+		if !seen[node.key()] {
+			seen[node.key()] = true
+			nodes = append(nodes, node)
+		}
+	}
+	for _, server := range r.servers {
+		ips, err := server.lookup()
+		if err != nil {
+			log.Errorf("resolve polaris server %s: %v", server.hostname, err)
+			for _, node := range current {
+				if node.scheme == server.scheme && node.hostname == server.hostname && node.host.Port == server.host.Port {
+					keep(node)
+				}
+			}
+			continue
+		}
+		for _, ip := range ips {
+			host := server
+			host.host.Address = ip
+			node, ok := current[host.key()]
+			if !ok {
+				node = r.newNode(host)
+				log.Infof("add polaris node %s (%s)", node.host, node.hostname)
+			}
+			keep(node)
+		}
+	}
+
+	for key, node := range current {
+		if !seen[key] {
+			log.Infof("remove polaris node %s (%s)", node.host, node.hostname)
+		}
+	}
+	if len(nodes) == 0 {
+		return
+	}
+
+	r.lock.Lock()
+	r.rss = nodes
+	r.lock.Unlock()
+}
+
+// refresh 按 interval 周期性重新解析节点主机名
+func (r *WeightRoundRobinBalance) refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Update()
+		}
+	}
 }
@@ -0,0 +1,125 @@
+package polaris
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	jsoniter "github.com/json-iterator/go"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credential 登录北极星使用的凭据，Token 不为空时直接使用该 token，不再调用登录接口
+type Credential struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// CredentialProvider 提供登录北极星的凭据，每次登录前都会调用，以便凭据轮换后重新登录
+type CredentialProvider interface {
+	Credential(ctx context.Context) (Credential, error)
+}
+
+// CredentialProviderFunc 函数形式的 CredentialProvider
+type CredentialProviderFunc func(ctx context.Context) (Credential, error)
+
+func (f CredentialProviderFunc) Credential(ctx context.Context) (Credential, error) {
+	return f(ctx)
+}
+
+// StaticCredentials 固定的账号密码
+func StaticCredentials(username, password string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credential, error) {
+		return Credential{Username: username, Password: password}, nil
+	})
+}
+
+// TokenCredentials 预先签发的 token
+func TokenCredentials(token string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credential, error) {
+		return Credential{Token: token}, nil
+	})
+}
+
+// EnvCredentials 从环境变量读取凭据，未设置的变量名使用默认值
+// POLARIS_USERNAME、POLARIS_PASSWORD、POLARIS_TOKEN
+type EnvCredentials struct {
+	UsernameKey string
+	PasswordKey string
+	TokenKey    string
+}
+
+func (e EnvCredentials) Credential(context.Context) (Credential, error) {
+	cred := Credential{
+		Username: os.Getenv(envKey(e.UsernameKey, "POLARIS_USERNAME")),
+		Password: os.Getenv(envKey(e.PasswordKey, "POLARIS_PASSWORD")),
+		Token:    os.Getenv(envKey(e.TokenKey, "POLARIS_TOKEN")),
+	}
+	if cred.Username == "" && cred.Token == "" {
+		return Credential{}, errors.New("polaris: no credentials in environment")
+	}
+	return cred, nil
+}
+
+func envKey(key, defaultKey string) string {
+	if key == "" {
+		return defaultKey
+	}
+	return key
+}
+
+// FileCredentials 从文件读取凭据，适用于挂载的 secret，文件修改后重新读取。
+// 文件内容为 {"username":"","password":"","token":""} 格式的 JSON，非 JSON 内容视为 token
+type FileCredentials struct {
+	Path string
+
+	lock    sync.Mutex
+	modTime time.Time
+	cred    Credential
+}
+
+func (f *FileCredentials) Credential(context.Context) (Credential, error) {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if !f.modTime.IsZero() && !info.ModTime().After(f.modTime) {
+		return f.cred, nil
+	}
+
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return Credential{}, err
+	}
+	content := strings.TrimSpace(string(data))
+	var cred Credential
+	if strings.HasPrefix(content, "{") {
+		var file struct {
+			Username string `json:"username"`
+			Name     string `json:"name"`
+			Password string `json:"password"`
+			Token    string `json:"token"`
+		}
+		if err := jsoniter.UnmarshalFromString(content, &file); err != nil {
+			return Credential{}, fmt.Errorf("polaris: parse credentials file %s: %w", f.Path, err)
+		}
+		cred = Credential{Username: file.Username, Password: file.Password, Token: file.Token}
+		if cred.Username == "" {
+			cred.Username = file.Name
+		}
+	} else {
+		cred = Credential{Token: content}
+	}
+	if cred.Username == "" && cred.Token == "" {
+		return Credential{}, fmt.Errorf("polaris: no credentials in file %s", f.Path)
+	}
+
+	f.cred, f.modTime = cred, info.ModTime()
+	return cred, nil
+}
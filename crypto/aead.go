@@ -0,0 +1,114 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// AesGcmCryptor AES-GCM 加密，密文格式为 base64(nonce + 密文 + tag)，支持 16、24、32 字节密钥
+type AesGcmCryptor struct {
+}
+
+// GenerateKey 生成 32 字节密钥
+func (c *AesGcmCryptor) GenerateKey() ([]byte, error) {
+	return randomKey(32)
+}
+
+// Encrypt 加密明文并 base64 编码
+func (c *AesGcmCryptor) Encrypt(plaintext string, key []byte) (string, error) {
+	aead, err := newAesGcm(key)
+	if err != nil {
+		return "", err
+	}
+	return aeadEncrypt(aead, plaintext)
+}
+
+// Decrypt base64 解码并解密
+func (c *AesGcmCryptor) Decrypt(ciphertext string, key []byte) (string, error) {
+	aead, err := newAesGcm(key)
+	if err != nil {
+		return "", err
+	}
+	return aeadDecrypt(aead, ciphertext)
+}
+
+// ChaCha20Poly1305Cryptor ChaCha20-Poly1305 加密，密文格式为 base64(nonce + 密文 + tag)，密钥为 32 字节
+type ChaCha20Poly1305Cryptor struct {
+}
+
+// GenerateKey 生成 32 字节密钥
+func (c *ChaCha20Poly1305Cryptor) GenerateKey() ([]byte, error) {
+	return randomKey(chacha20poly1305.KeySize)
+}
+
+// Encrypt 加密明文并 base64 编码
+func (c *ChaCha20Poly1305Cryptor) Encrypt(plaintext string, key []byte) (string, error) {
+	aead, err := newChaCha20Poly1305(key)
+	if err != nil {
+		return "", err
+	}
+	return aeadEncrypt(aead, plaintext)
+}
+
+// Decrypt base64 解码并解密
+func (c *ChaCha20Poly1305Cryptor) Decrypt(ciphertext string, key []byte) (string, error) {
+	aead, err := newChaCha20Poly1305(key)
+	if err != nil {
+		return "", err
+	}
+	return aeadDecrypt(aead, ciphertext)
+}
+
+func newAesGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func newChaCha20Poly1305(key []byte) (cipher.AEAD, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return aead, nil
+}
+
+// aeadEncrypt 使用随机 nonce 加密，nonce 放在密文前面
+func aeadEncrypt(aead cipher.AEAD, plaintext string) (string, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func aeadDecrypt(aead cipher.AEAD, ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return "", fmt.Errorf("%w: ciphertext too short", ErrInvalidCiphertext)
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	return string(plaintext), nil
+}
+
+func randomKey(size int) ([]byte, error) {
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -4,20 +4,39 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
-	"errors"
-	"math/rand"
+	"fmt"
+	"io"
 )
 
+// 版本化密文格式为 aesMagic + 版本号 + IV/nonce + 密文，旧版本密文没有前缀，
+// 使用密钥作为 CBC 的 IV，与北极星服务端及其他 SDK 兼容
+const (
+	// aesVersionCBC CBC 模式，随机 IV
+	aesVersionCBC = byte(1)
+	// aesVersionGCM GCM 模式，随机 nonce，带认证
+	aesVersionGCM = byte(2)
+)
+
+var aesMagic = []byte("\x89PAE")
+
 // AesCryptor AES cryptor
 type AesCryptor struct {
+	// Authenticated 为 true 时使用 GCM 模式加密，密文被篡改或密钥错误时解密失败
+	Authenticated bool
+	// Legacy 为 true 时生成使用密钥作为 IV 的旧格式密文，用于与北极星控制台及其他 SDK 互通，
+	// 优先级高于 Authenticated
+	Legacy bool
+	// Rand 生成密钥、IV 使用的随机数来源，为 nil 时使用 crypto/rand
+	Rand io.Reader
 }
 
 // GenerateKey generate key
 func (c *AesCryptor) GenerateKey() ([]byte, error) {
 	key := make([]byte, 16)
-	_, err := rand.Read(key)
-	if err != nil {
+	if _, err := io.ReadFull(c.rand(), key); err != nil {
 		return nil, err
 	}
 	return key, nil
@@ -25,29 +44,128 @@ func (c *AesCryptor) GenerateKey() ([]byte, error) {
 
 // Encrypt AES encrypt plaintext and base64 encode ciphertext
 func (c *AesCryptor) Encrypt(plaintext string, key []byte) (string, error) {
-	ciphertext, err := c.doEncrypt([]byte(plaintext), key)
+	var ciphertext []byte
+	var err error
+	switch {
+	case c.Legacy:
+		ciphertext, err = c.doEncrypt([]byte(plaintext), key)
+	case c.Authenticated:
+		ciphertext, err = c.encryptGCM([]byte(plaintext), key)
+	default:
+		ciphertext, err = c.encryptCBC([]byte(plaintext), key)
+	}
 	if err != nil {
 		return "", err
 	}
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Decrypt base64 decode ciphertext and AES decrypt
+// Decrypt base64 decode ciphertext and AES decrypt，支持版本化格式及旧格式密文
 func (c *AesCryptor) Decrypt(ciphertext string, key []byte) (string, error) {
 	ciphertextBytes, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	if len(ciphertextBytes) <= len(aesMagic) || !bytes.HasPrefix(ciphertextBytes, aesMagic) {
+		plaintext, err := c.doDecrypt(ciphertextBytes, key)
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	}
+
+	var plaintext []byte
+	body := ciphertextBytes[len(aesMagic)+1:]
+	switch version := ciphertextBytes[len(aesMagic)]; version {
+	case aesVersionCBC:
+		plaintext, err = c.decryptCBC(body, key)
+	case aesVersionGCM:
+		plaintext, err = c.decryptGCM(body, key)
+	default:
+		err = fmt.Errorf("%w: unknown version %d", ErrInvalidCiphertext, version)
 	}
-	plaintext, err := c.doDecrypt(ciphertextBytes, key)
 	if err != nil {
+		// 旧格式密文恰好以 aesMagic 开头时按旧格式解密
+		if legacy, legacyErr := c.doDecrypt(ciphertextBytes, key); legacyErr == nil {
+			return string(legacy), nil
+		}
 		return "", err
 	}
 	return string(plaintext), nil
 }
 
-// encrypt AES encryption
+func (c *AesCryptor) rand() io.Reader {
+	if c.Rand != nil {
+		return c.Rand
+	}
+	return rand.Reader
+}
+
+// encryptCBC CBC 模式加密，使用随机 IV
+func (c *AesCryptor) encryptCBC(plaintext []byte, key []byte) ([]byte, error) {
+	block, err := newAesBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	paddingData := pkcs7Padding(plaintext, block.BlockSize())
+	header := len(aesMagic) + 1
+	ciphertext := make([]byte, header+block.BlockSize()+len(paddingData))
+	copy(ciphertext, aesMagic)
+	ciphertext[len(aesMagic)] = aesVersionCBC
+	iv := ciphertext[header : header+block.BlockSize()]
+	if _, err := io.ReadFull(c.rand(), iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext[header+block.BlockSize():], paddingData)
+	return ciphertext, nil
+}
+
+func (c *AesCryptor) decryptCBC(ciphertext []byte, key []byte) ([]byte, error) {
+	block, err := newAesBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	blockSize := block.BlockSize()
+	if len(ciphertext) < 2*blockSize {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrInvalidCiphertext)
+	}
+	return cbcDecrypt(block, ciphertext[:blockSize], ciphertext[blockSize:])
+}
+
+// encryptGCM GCM 模式加密，使用随机 nonce
+func (c *AesCryptor) encryptGCM(plaintext []byte, key []byte) ([]byte, error) {
+	aead, err := newAesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	header := append(append([]byte{}, aesMagic...), aesVersionGCM)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(c.rand(), nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(append(header, nonce...), nonce, plaintext, header), nil
+}
+
+func (c *AesCryptor) decryptGCM(ciphertext []byte, key []byte) ([]byte, error) {
+	aead, err := newAesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrInvalidCiphertext)
+	}
+	header := append(append([]byte{}, aesMagic...), aesVersionGCM)
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	return plaintext, nil
+}
+
+// doEncrypt 旧格式加密，使用密钥作为 IV
 func (c *AesCryptor) doEncrypt(plaintext []byte, key []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
+	block, err := newAesBlock(key)
 	if err != nil {
 		return nil, err
 	}
@@ -59,21 +177,31 @@ func (c *AesCryptor) doEncrypt(plaintext []byte, key []byte) ([]byte, error) {
 	return ciphertext, nil
 }
 
-// Decrypt AES decryption
+// doDecrypt 旧格式解密
 func (c *AesCryptor) doDecrypt(ciphertext []byte, key []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
+	block, err := newAesBlock(key)
 	if err != nil {
 		return nil, err
 	}
-	blockSize := block.BlockSize()
-	blockMode := cipher.NewCBCDecrypter(block, key[:blockSize])
-	paddingPlaintext := make([]byte, len(ciphertext))
-	blockMode.CryptBlocks(paddingPlaintext, ciphertext)
-	plaintext, err := pkcs7UnPadding(paddingPlaintext)
+	return cbcDecrypt(block, key[:block.BlockSize()], ciphertext)
+}
+
+func newAesBlock(key []byte) (cipher.Block, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
 	}
-	return plaintext, nil
+	return block, nil
+}
+
+func cbcDecrypt(block cipher.Block, iv, ciphertext []byte) ([]byte, error) {
+	blockSize := block.BlockSize()
+	if len(ciphertext) == 0 || len(ciphertext)%blockSize != 0 {
+		return nil, fmt.Errorf("%w: ciphertext is not a multiple of the block size", ErrInvalidCiphertext)
+	}
+	paddingPlaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(paddingPlaintext, ciphertext)
+	return pkcs7UnPadding(paddingPlaintext, blockSize)
 }
 
 func pkcs7Padding(data []byte, blockSize int) []byte {
@@ -82,11 +210,19 @@ func pkcs7Padding(data []byte, blockSize int) []byte {
 	return append(data, padText...)
 }
 
-func pkcs7UnPadding(data []byte) ([]byte, error) {
+// pkcs7UnPadding 去除填充，填充长度及每个填充字节都必须正确
+func pkcs7UnPadding(data []byte, blockSize int) ([]byte, error) {
 	length := len(data)
-	if length == 0 {
-		return nil, errors.New("invalid encryption data")
+	if length == 0 || length%blockSize != 0 {
+		return nil, fmt.Errorf("%w: invalid encryption data", ErrInvalidCiphertext)
 	}
 	unPadding := int(data[length-1])
-	return data[:(length - unPadding)], nil
+	if unPadding == 0 || unPadding > blockSize {
+		return nil, fmt.Errorf("%w: bad padding", ErrInvalidCiphertext)
+	}
+	padText := bytes.Repeat([]byte{byte(unPadding)}, unPadding)
+	if subtle.ConstantTimeCompare(data[length-unPadding:], padText) != 1 {
+		return nil, fmt.Errorf("%w: bad padding", ErrInvalidCiphertext)
+	}
+	return data[:length-unPadding], nil
 }
@@ -0,0 +1,212 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+var (
+	testKey       = []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	testIV        = []byte{0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f}
+	testPlaintext = "polaris config"
+)
+
+// 密文由 openssl enc -aes-128-cbc 及标准库 crypto/cipher 的 GCM 独立生成
+var aesKnownAnswers = []struct {
+	name       string
+	cryptor    AesCryptor
+	ciphertext string
+}{
+	{
+		name:       "legacy",
+		cryptor:    AesCryptor{Legacy: true},
+		ciphertext: "7ACRNlJ8oMTftmwFU0vcWA==",
+	},
+	{
+		name:       "cbc",
+		cryptor:    AesCryptor{},
+		ciphertext: "iVBBRQEQERITFBUWFxgZGhscHR4fEtPQXXyCO5Cs7l0njrKuaA==",
+	},
+	{
+		name:       "gcm",
+		cryptor:    AesCryptor{Authenticated: true},
+		ciphertext: "iVBBRQIQERITFBUWFxgZGhu0QW/OfSbFz3SyM5OuQAXgwBuMkme+0UlqZ1jQb4A=",
+	},
+}
+
+func TestAesCryptorEncryptKnownAnswer(t *testing.T) {
+	for _, tt := range aesKnownAnswers {
+		t.Run(tt.name, func(t *testing.T) {
+			cryptor := tt.cryptor
+			cryptor.Rand = bytes.NewReader(testIV)
+			ciphertext, err := cryptor.Encrypt(testPlaintext, testKey)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if ciphertext != tt.ciphertext {
+				t.Errorf("Encrypt() = %s, want %s", ciphertext, tt.ciphertext)
+			}
+		})
+	}
+}
+
+// AES tag 需要能被北极星控制台及其他 SDK 解密，注册的 AES 必须生成旧格式密文
+func TestRegisteredAesFormats(t *testing.T) {
+	cryptor, err := GetCryptor(AlgoAES)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ciphertext, err := cryptor.Encrypt(testPlaintext, testKey); err != nil || ciphertext != aesKnownAnswers[0].ciphertext {
+		t.Errorf("%s Encrypt() = %s, %v, want %s", AlgoAES, ciphertext, err, aesKnownAnswers[0].ciphertext)
+	}
+
+	cryptor, err = GetCryptor(AlgoAESV2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := cryptor.Encrypt(testPlaintext, testKey)
+	if err != nil {
+		t.Fatalf("%s Encrypt() error = %v", AlgoAESV2, err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(ciphertext)
+	if !bytes.HasPrefix(data, append(aesMagic, aesVersionGCM)) {
+		t.Errorf("%s Encrypt() = %x, want versioned gcm format", AlgoAESV2, data)
+	}
+	if plaintext, err := cryptor.Decrypt(ciphertext, testKey); err != nil || plaintext != testPlaintext {
+		t.Errorf("%s Decrypt() = %q, %v, want %q", AlgoAESV2, plaintext, err, testPlaintext)
+	}
+}
+
+func TestAesCryptorDecryptKnownAnswer(t *testing.T) {
+	for _, tt := range aesKnownAnswers {
+		t.Run(tt.name, func(t *testing.T) {
+			// 解密不依赖加密时的配置
+			cryptor := AesCryptor{}
+			plaintext, err := cryptor.Decrypt(tt.ciphertext, testKey)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if plaintext != testPlaintext {
+				t.Errorf("Decrypt() = %q, want %q", plaintext, testPlaintext)
+			}
+		})
+	}
+}
+
+func TestAesCryptorRoundTrip(t *testing.T) {
+	for _, cryptor := range []AesCryptor{{}, {Authenticated: true}, {Legacy: true}} {
+		key, err := cryptor.GenerateKey()
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		for _, plaintext := range []string{"", "a", "0123456789abcdef", "中文配置内容"} {
+			ciphertext, err := cryptor.Encrypt(plaintext, key)
+			if err != nil {
+				t.Fatalf("Encrypt(%q) error = %v", plaintext, err)
+			}
+			got, err := cryptor.Decrypt(ciphertext, key)
+			if err != nil {
+				t.Fatalf("Decrypt(%q) error = %v", plaintext, err)
+			}
+			if got != plaintext {
+				t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+			}
+		}
+	}
+}
+
+func TestAesCryptorRandomIV(t *testing.T) {
+	cryptor := AesCryptor{}
+	first, _ := cryptor.Encrypt(testPlaintext, testKey)
+	second, _ := cryptor.Encrypt(testPlaintext, testKey)
+	if first == second {
+		t.Errorf("Encrypt() returned identical ciphertexts %s", first)
+	}
+}
+
+func TestAesCryptorGenerateKey(t *testing.T) {
+	cryptor := AesCryptor{Rand: bytes.NewReader(testKey)}
+	key, err := cryptor.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	if !bytes.Equal(key, testKey) {
+		t.Errorf("GenerateKey() = %x, want %x", key, testKey)
+	}
+
+	cryptor = AesCryptor{Rand: bytes.NewReader(testKey[:8])}
+	if _, err := cryptor.GenerateKey(); err == nil {
+		t.Error("GenerateKey() with short random source error = nil")
+	}
+}
+
+func TestAesCryptorDecryptInvalid(t *testing.T) {
+	otherKey := bytes.Repeat([]byte{0xff}, 16)
+	tests := []struct {
+		name       string
+		ciphertext string
+		key        []byte
+		want       error
+	}{
+		{"not base64", "!!!", testKey, ErrInvalidCiphertext},
+		{"empty", "", testKey, ErrInvalidCiphertext},
+		{"not block aligned", base64.StdEncoding.EncodeToString(make([]byte, 15)), testKey, ErrInvalidCiphertext},
+		{"bad padding", base64.StdEncoding.EncodeToString(make([]byte, 16)), testKey, ErrInvalidCiphertext},
+		{"invalid key", aesKnownAnswers[0].ciphertext, testKey[:5], ErrInvalidKey},
+		{"gcm wrong key", aesKnownAnswers[2].ciphertext, otherKey, ErrInvalidCiphertext},
+		{"gcm tampered", tamper(aesKnownAnswers[2].ciphertext), testKey, ErrInvalidCiphertext},
+		{"cbc truncated", base64.StdEncoding.EncodeToString(append(append([]byte{}, aesMagic...), aesVersionCBC)), testKey, ErrInvalidCiphertext},
+		{"unknown version", base64.StdEncoding.EncodeToString(append(append([]byte{}, aesMagic...), 9, 0, 0)), testKey, ErrInvalidCiphertext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cryptor := AesCryptor{}
+			_, err := cryptor.Decrypt(tt.ciphertext, tt.key)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Decrypt() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPkcs7UnPadding(t *testing.T) {
+	block := bytes.Repeat([]byte{'a'}, 16)
+	tests := []struct {
+		name    string
+		padding []byte
+		want    int
+		wantErr bool
+	}{
+		{"one byte", []byte{1}, 15, false},
+		{"full block", bytes.Repeat([]byte{16}, 16), 0, false},
+		{"zero", []byte{0}, 0, true},
+		{"too long", []byte{17}, 0, true},
+		{"inconsistent", []byte{3, 2, 3}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append(append([]byte{}, block[:16-len(tt.padding)]...), tt.padding...)
+			got, err := pkcs7UnPadding(data, 16)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCiphertext) {
+					t.Errorf("pkcs7UnPadding() error = %v, want ErrInvalidCiphertext", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pkcs7UnPadding() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("pkcs7UnPadding() length = %d, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func tamper(ciphertext string) string {
+	data, _ := base64.StdEncoding.DecodeString(ciphertext)
+	data[len(data)-1] ^= 0x01
+	return base64.StdEncoding.EncodeToString(data)
+}
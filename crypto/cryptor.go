@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 内置加密算法名称，与配置文件 internal-encryptalgo tag 的值对应
+const (
+	// AlgoAES 与北极星控制台及其他 SDK 兼容的 AES-CBC，使用密钥作为 IV
+	AlgoAES = "AES"
+	// AlgoAESV2 版本化格式的 AES，随机 nonce 的 GCM 模式，只有本 SDK 能够解密
+	AlgoAESV2 = "AES-V2"
+	// AlgoAESGCM AES-GCM，密文为 nonce + 密文
+	AlgoAESGCM = "AES-GCM"
+	// AlgoChaCha20Poly1305 ChaCha20-Poly1305，密文为 nonce + 密文
+	AlgoChaCha20Poly1305 = "ChaCha20-Poly1305"
+)
+
+var (
+	// ErrUnsupportedAlgo 加密算法未注册
+	ErrUnsupportedAlgo = errors.New("unsupported encrypt algo")
+	// ErrInvalidKey 数据密钥长度不符合算法要求
+	ErrInvalidKey = errors.New("invalid data key")
+	// ErrInvalidCiphertext 密文格式错误或校验失败，通常是密钥不匹配或密文被篡改
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Cryptor 配置加密算法，密文为 base64 编码的字符串
+type Cryptor interface {
+	// GenerateKey 生成数据密钥
+	GenerateKey() ([]byte, error)
+	// Encrypt 加密明文
+	Encrypt(plaintext string, key []byte) (string, error)
+	// Decrypt 解密密文
+	Decrypt(ciphertext string, key []byte) (string, error)
+}
+
+var cryptors = struct {
+	sync.RWMutex
+	algos map[string]Cryptor
+}{algos: map[string]Cryptor{}}
+
+func init() {
+	RegisterCryptor(AlgoAES, &AesCryptor{Legacy: true})
+	RegisterCryptor(AlgoAESV2, &AesCryptor{Authenticated: true})
+	RegisterCryptor(AlgoAESGCM, &AesGcmCryptor{})
+	RegisterCryptor(AlgoChaCha20Poly1305, &ChaCha20Poly1305Cryptor{})
+}
+
+// RegisterCryptor 注册加密算法，算法名称不区分大小写，同名算法会被替换
+func RegisterCryptor(algo string, cryptor Cryptor) {
+	if algo == "" || cryptor == nil {
+		panic("crypto: register cryptor with empty algo or nil cryptor")
+	}
+	cryptors.Lock()
+	defer cryptors.Unlock()
+	cryptors.algos[strings.ToUpper(algo)] = cryptor
+}
+
+// GetCryptor 获取加密算法，未注册时返回包装了 ErrUnsupportedAlgo 的错误
+func GetCryptor(algo string) (Cryptor, error) {
+	cryptors.RLock()
+	defer cryptors.RUnlock()
+	cryptor, ok := cryptors.algos[strings.ToUpper(algo)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedAlgo, algo)
+	}
+	return cryptor, nil
+}
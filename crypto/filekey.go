@@ -0,0 +1,233 @@
+package crypto
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nxsre/polaris-go/internal/fileutil"
+	"os"
+	"sync"
+)
+
+// FileKeyProviderName 本地文件主密钥提供者的名称
+const FileKeyProviderName = "local-file"
+
+// masterKeySize 主密钥长度，使用 AES-256-GCM 包装数据密钥
+const masterKeySize = 32
+
+// masterKeyFile 主密钥文件内容，Keys 为 主密钥 ID -> base64 编码的主密钥
+type masterKeyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// FileKeyProvider 使用保存在本地文件中的主密钥包装数据密钥，适用于没有 KMS 的环境。
+// 包装结果格式为 主密钥 ID 长度(1 字节) + 主密钥 ID + nonce + 密文，轮换后旧的主密钥仍保留用于解包。
+// 文件修改后重新加载，其他进程轮换的主密钥无需重启即可使用
+type FileKeyProvider struct {
+	path string
+
+	lock sync.RWMutex
+	// info 上次加载或写入时的文件信息，修改时间或大小变化时重新加载
+	info    os.FileInfo
+	current string
+	keys    map[string][]byte
+}
+
+// NewFileKeyProvider 从 path 加载主密钥，文件不存在时生成主密钥并以 0600 权限创建文件
+func NewFileKeyProvider(path string) (*FileKeyProvider, error) {
+	p := &FileKeyProvider{path: path, keys: map[string][]byte{}}
+	err := p.reload()
+	if errors.Is(err, os.ErrNotExist) {
+		if err := p.Rotate(); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// reload 文件修改时间或大小变化时重新加载主密钥，修改时间精度较低的文件系统上也能发现轮换及删除
+func (p *FileKeyProvider) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.info != nil && info.ModTime().Equal(p.info.ModTime()) && info.Size() == p.info.Size() {
+		return nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+	file := &masterKeyFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return fmt.Errorf("parse master key file %s: %w", p.path, err)
+	}
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != masterKeySize {
+			return fmt.Errorf("master key file %s: %w: bad master key %q", p.path, ErrInvalidKey, id)
+		}
+		keys[id] = key
+	}
+	if _, ok := keys[file.Current]; !ok {
+		return fmt.Errorf("master key file %s: %w %q", p.path, ErrUnknownMasterKey, file.Current)
+	}
+	p.current, p.keys, p.info = file.Current, keys, info
+	return nil
+}
+
+// Name 提供者名称
+func (p *FileKeyProvider) Name() string {
+	return FileKeyProviderName
+}
+
+// CurrentKeyID 当前用于包装数据密钥的主密钥 ID，重新加载文件失败时返回上次加载的 ID
+func (p *FileKeyProvider) CurrentKeyID() string {
+	_ = p.reload()
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.current
+}
+
+// WrapKey 使用当前主密钥包装数据密钥
+func (p *FileKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	p.lock.RLock()
+	id, key := p.current, p.keys[p.current]
+	p.lock.RUnlock()
+
+	aead, err := newAesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte{byte(len(id))}, id...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(append(header, nonce...), nonce, dataKey, header), nil
+}
+
+// UnwrapKey 使用包装时的主密钥解包数据密钥
+func (p *FileKeyProvider) UnwrapKey(ctx context.Context, wrappedKey []byte) ([]byte, error) {
+	if len(wrappedKey) == 0 || len(wrappedKey) < 1+int(wrappedKey[0]) {
+		return nil, fmt.Errorf("%w: wrapped key too short", ErrInvalidCiphertext)
+	}
+	header := wrappedKey[:1+int(wrappedKey[0])]
+	id := string(header[1:])
+
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	p.lock.RLock()
+	key, ok := p.keys[id]
+	p.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownMasterKey, id)
+	}
+
+	aead, err := newAesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed := wrappedKey[len(header):]
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("%w: wrapped key too short", ErrInvalidCiphertext)
+	}
+	dataKey, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	return dataKey, nil
+}
+
+// Rotate 生成新的主密钥作为当前主密钥并写入文件，旧的主密钥保留用于解包，
+// 轮换后使用 RewrapKey 重新包装已发布的数据密钥
+func (p *FileKeyProvider) Rotate() error {
+	key, err := randomKey(masterKeySize)
+	if err != nil {
+		return err
+	}
+	rawID, err := randomKey(8)
+	if err != nil {
+		return err
+	}
+	id := hex.EncodeToString(rawID)
+
+	if err := p.reload(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	keys := make(map[string][]byte, len(p.keys)+1)
+	for oldID, oldKey := range p.keys {
+		keys[oldID] = oldKey
+	}
+	keys[id] = key
+	info, err := p.save(id, keys)
+	if err != nil {
+		return err
+	}
+	p.current, p.keys, p.info = id, keys, info
+	return nil
+}
+
+// RemoveKey 删除不再使用的旧主密钥，删除前需要确认使用它包装的数据密钥都已重新包装
+func (p *FileKeyProvider) RemoveKey(id string) error {
+	if err := p.reload(); err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if id == p.current {
+		return errors.New("cannot remove current master key")
+	}
+	if _, ok := p.keys[id]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownMasterKey, id)
+	}
+	keys := make(map[string][]byte, len(p.keys))
+	for oldID, oldKey := range p.keys {
+		if oldID != id {
+			keys[oldID] = oldKey
+		}
+	}
+	info, err := p.save(p.current, keys)
+	if err != nil {
+		return err
+	}
+	p.keys, p.info = keys, info
+	return nil
+}
+
+// save 原子地写入主密钥文件，返回写入后的文件信息
+func (p *FileKeyProvider) save(current string, keys map[string][]byte) (os.FileInfo, error) {
+	file := &masterKeyFile{Current: current, Keys: make(map[string]string, len(keys))}
+	for id, key := range keys {
+		file.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fileutil.WriteFileAtomic(p.path, data); err != nil {
+		return nil, err
+	}
+	return os.Stat(p.path)
+}
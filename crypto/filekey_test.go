@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileKeyProviderRotate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys", "master.json")
+	provider, err := NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider() error = %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("master key file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	dataKey := []byte("0123456789abcdef")
+	wrapped, err := provider.WrapKey(ctx, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey() error = %v", err)
+	}
+	oldID := provider.CurrentKeyID()
+	if err := provider.Rotate(); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if provider.CurrentKeyID() == oldID {
+		t.Fatal("CurrentKeyID() unchanged after Rotate()")
+	}
+	if err := provider.RemoveKey(provider.CurrentKeyID()); err == nil {
+		t.Error("RemoveKey() current key error = nil")
+	}
+
+	// 轮换后重新包装，删除旧主密钥后仍能解包
+	rewrapped, err := RewrapKey(ctx, provider, wrapped)
+	if err != nil {
+		t.Fatalf("RewrapKey() error = %v", err)
+	}
+	if err := provider.RemoveKey(oldID); err != nil {
+		t.Fatalf("RemoveKey() error = %v", err)
+	}
+	if got, err := provider.UnwrapKey(ctx, rewrapped); err != nil || !bytes.Equal(got, dataKey) {
+		t.Errorf("UnwrapKey() rewrapped = %q, %v, want %q", got, err, dataKey)
+	}
+	if _, err := provider.UnwrapKey(ctx, wrapped); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("UnwrapKey() with removed key error = %v, want ErrUnknownMasterKey", err)
+	}
+
+	// 重新加载文件得到相同的主密钥
+	reloaded, err := NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider() existing file error = %v", err)
+	}
+	if got, err := reloaded.UnwrapKey(ctx, rewrapped); err != nil || !bytes.Equal(got, dataKey) {
+		t.Errorf("UnwrapKey() after reload = %q, %v, want %q", got, err, dataKey)
+	}
+}
+
+func TestFileKeyProviderReload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "master.json")
+	a, err := NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他进程轮换主密钥后无需重新创建即可使用
+	if err := a.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if b.CurrentKeyID() != a.CurrentKeyID() {
+		t.Errorf("CurrentKeyID() = %s after another provider rotated, want %s", b.CurrentKeyID(), a.CurrentKeyID())
+	}
+	wrapped, err := a.WrapKey(ctx, []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.UnwrapKey(ctx, wrapped); err != nil {
+		t.Errorf("UnwrapKey() of key wrapped by another provider error = %v", err)
+	}
+
+	// 轮换时保留其他进程写入的主密钥
+	if err := b.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.UnwrapKey(ctx, wrapped); err != nil {
+		t.Errorf("UnwrapKey() after another provider rotated again error = %v", err)
+	}
+}
+
+func TestFileKeyProviderInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"malformed.json": "{",
+		"badkey.json":    `{"current":"k1","keys":{"k1":"c2hvcnQ="}}`,
+		"nocurrent.json": `{"current":"k2","keys":{"k1":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewFileKeyProvider(path); err == nil {
+			t.Errorf("NewFileKeyProvider(%s) error = nil", name)
+		}
+	}
+	if _, err := (&FileKeyProvider{}).UnwrapKey(context.Background(), []byte{5, 'a'}); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("UnwrapKey() short key error = %v, want ErrInvalidCiphertext", err)
+	}
+}
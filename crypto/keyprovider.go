@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrUnknownKeyProvider 密钥提供者未注册
+	ErrUnknownKeyProvider = errors.New("unknown key provider")
+	// ErrUnknownMasterKey 包装数据密钥的主密钥不存在，可能已被删除
+	ErrUnknownMasterKey = errors.New("unknown master key")
+)
+
+// KeyProvider 使用主密钥包装、解包数据密钥，主密钥不离开提供者。
+// 包装结果需要记录使用的主密钥，轮换主密钥后仍能解包旧的数据密钥
+type KeyProvider interface {
+	// Name 提供者名称，发布时写入配置文件 tag，获取时据此选择提供者解包
+	Name() string
+	// WrapKey 使用当前主密钥包装数据密钥
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	// UnwrapKey 解包数据密钥
+	UnwrapKey(ctx context.Context, wrappedKey []byte) ([]byte, error)
+}
+
+var keyProviders = struct {
+	sync.RWMutex
+	providers map[string]KeyProvider
+}{providers: map[string]KeyProvider{}}
+
+// RegisterKeyProvider 注册密钥提供者，同名提供者会被替换
+func RegisterKeyProvider(provider KeyProvider) {
+	if provider == nil || provider.Name() == "" {
+		panic("crypto: register nil key provider or key provider with empty name")
+	}
+	keyProviders.Lock()
+	defer keyProviders.Unlock()
+	keyProviders.providers[provider.Name()] = provider
+}
+
+// GetKeyProvider 获取密钥提供者，未注册时返回包装了 ErrUnknownKeyProvider 的错误
+func GetKeyProvider(name string) (KeyProvider, error) {
+	keyProviders.RLock()
+	defer keyProviders.RUnlock()
+	provider, ok := keyProviders.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKeyProvider, name)
+	}
+	return provider, nil
+}
+
+// RewrapKey 解包数据密钥后使用提供者当前的主密钥重新包装，用于主密钥轮换，数据密钥本身不变
+func RewrapKey(ctx context.Context, provider KeyProvider, wrappedKey []byte) ([]byte, error) {
+	dataKey, err := provider.UnwrapKey(ctx, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return provider.WrapKey(ctx, dataKey)
+}
+
+// KMSKeyProvider 通过回调接入 KMS 服务，Encrypt、Decrypt 分别调用 KMS 的加密、解密接口，
+// 主密钥的版本由 KMS 记录在密文中
+type KMSKeyProvider struct {
+	ProviderName string
+	Encrypt      func(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt      func(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// Name 提供者名称
+func (p *KMSKeyProvider) Name() string {
+	return p.ProviderName
+}
+
+// WrapKey 调用 KMS 加密数据密钥
+func (p *KMSKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	return p.Encrypt(ctx, dataKey)
+}
+
+// UnwrapKey 调用 KMS 解密数据密钥
+func (p *KMSKeyProvider) UnwrapKey(ctx context.Context, wrappedKey []byte) ([]byte, error) {
+	return p.Decrypt(ctx, wrappedKey)
+}
@@ -0,0 +1,87 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+)
+
+// DefaultRSAKeyBits 默认 RSA 密钥长度
+const DefaultRSAKeyBits = 2048
+
+// GenerateRSAKey 生成 RSA 密钥对
+func GenerateRSAKey(bits int) (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, bits)
+}
+
+// EncodeRSAPublicKey 将公钥编码为北极星使用的 base64 PKCS#1 格式
+func EncodeRSAPublicKey(pub *rsa.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(pub))
+}
+
+// ParseRSAPublicKey 解析 base64 PKCS#1 格式的公钥
+func ParseRSAPublicKey(publicKey string) (*rsa.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKCS1PublicKey(der)
+}
+
+// EncodeRSAPrivateKey 将私钥编码为 PEM 格式的 PKCS#1 私钥
+func EncodeRSAPrivateKey(priv *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+}
+
+// ParseRSAPrivateKey 解析 PEM 格式的 PKCS#1 私钥
+func ParseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "RSA PRIVATE KEY" {
+		return nil, errors.New("invalid rsa private key pem")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// RSAEncrypt 使用公钥分段加密，与北极星服务端加密数据密钥的方式一致
+func RSAEncrypt(plaintext []byte, pub *rsa.PublicKey) ([]byte, error) {
+	segLen := pub.Size() - 11
+	buffer := bytes.Buffer{}
+	for start := 0; start < len(plaintext); start += segLen {
+		end := start + segLen
+		if end > len(plaintext) {
+			end = len(plaintext)
+		}
+		seg, err := rsa.EncryptPKCS1v15(rand.Reader, pub, plaintext[start:end])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(seg)
+	}
+	return buffer.Bytes(), nil
+}
+
+// RSADecrypt 使用私钥分段解密
+func RSADecrypt(ciphertext []byte, priv *rsa.PrivateKey) ([]byte, error) {
+	keySize := priv.Size()
+	if len(ciphertext) == 0 || len(ciphertext)%keySize != 0 {
+		return nil, errors.New("invalid rsa ciphertext length")
+	}
+	buffer := bytes.Buffer{}
+	for start := 0; start < len(ciphertext); start += keySize {
+		seg, err := rsa.DecryptPKCS1v15(nil, priv, ciphertext[start:start+keySize])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(seg)
+	}
+	return buffer.Bytes(), nil
+}
+
+// IsRSACiphertext 判断数据是否为 priv 对应公钥加密的密文，AES 数据密钥的长度不会是 RSA 分段长度的整数倍
+func IsRSACiphertext(data []byte, priv *rsa.PrivateKey) bool {
+	return priv != nil && len(data) > 0 && len(data)%priv.Size() == 0
+}
@@ -0,0 +1,214 @@
+package polaris
+
+import (
+	"bytes"
+	"context"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nxsre/polaris-go/log"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDebugBodySize = 1024
+	redacted             = "***"
+
+	tagKeyEncrypted = "internal-encrypted"
+	tagKeyDataKey   = "internal-datakey"
+)
+
+// defaultSensitiveFields 默认脱敏的 JSON 字段、请求头及查询参数名
+var defaultSensitiveFields = []string{
+	TokenHeader,
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"password",
+	"token",
+	"dataKey",
+	"privateKey",
+}
+
+// DebugConfig 请求调试日志配置
+type DebugConfig struct {
+	// Enabled 为所有请求记录调试日志，为 false 时仅记录使用 DebugContext 的请求
+	Enabled bool
+	// MaxBodySize 日志中请求体、响应体保留的最大字节数，默认 1024，小于 0 时不记录请求体、响应体
+	MaxBodySize int
+	// SensitiveFields 额外需要脱敏的 JSON 字段、请求头及查询参数名，不区分大小写
+	SensitiveFields []string
+}
+
+type debugKey struct{}
+
+// DebugContext 为使用该 ctx 的请求记录调试日志，未开启 WithDebug 时也会生效
+func DebugContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugKey{}, true)
+}
+
+func isDebug(ctx context.Context) bool {
+	debug, _ := ctx.Value(debugKey{}).(bool)
+	return debug
+}
+
+// debugTransport 记录脱敏后的请求方法、地址、状态码、耗时及截断后的请求体、响应体
+type debugTransport struct {
+	config    DebugConfig
+	sensitive map[string]bool
+	logger    log.Logger
+	next      http.RoundTripper
+}
+
+func newDebugTransport(config DebugConfig, logger log.Logger, next http.RoundTripper) *debugTransport {
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = defaultDebugBodySize
+	}
+	sensitive := map[string]bool{}
+	for _, fields := range [][]string{defaultSensitiveFields, config.SensitiveFields} {
+		for _, field := range fields {
+			sensitive[strings.ToLower(field)] = true
+		}
+	}
+	return &debugTransport{config: config, sensitive: sensitive, logger: logger, next: next}
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.config.Enabled && !isDebug(req.Context()) {
+		return t.next.RoundTrip(req)
+	}
+
+	reqBody := t.requestBody(req)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	latency := time.Since(start)
+	if err != nil {
+		t.logger.Infof("polaris request %s %s error=%v latency=%s headers=%s body=%s",
+			req.Method, t.redactURL(req.URL), err, latency, t.redactHeader(req.Header), reqBody)
+		return resp, err
+	}
+
+	respBody := t.responseBody(resp)
+	t.logger.Infof("polaris request %s %s status=%d latency=%s headers=%s body=%s response=%s",
+		req.Method, t.redactURL(req.URL), resp.StatusCode, latency, t.redactHeader(req.Header), reqBody, respBody)
+	return resp, nil
+}
+
+// requestBody 通过 GetBody 读取请求体，不影响实际发送的请求
+func (t *debugTransport) requestBody(req *http.Request) string {
+	if t.config.MaxBodySize < 0 || req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	return t.formatBody(data)
+}
+
+// responseBody 读取响应体后替换为内存中的副本
+func (t *debugTransport) responseBody(resp *http.Response) string {
+	if t.config.MaxBodySize < 0 || resp.Body == nil {
+		return ""
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	return t.formatBody(data)
+}
+
+// formatBody 脱敏并截断请求体、响应体，非 JSON 内容只截断
+func (t *debugTransport) formatBody(data []byte) string {
+	var body any
+	if err := jsoniter.Unmarshal(data, &body); err == nil {
+		if redactedBody, err := jsoniter.Marshal(t.redactValue(body)); err == nil {
+			data = redactedBody
+		}
+	}
+	if len(data) > t.config.MaxBodySize {
+		return string(data[:t.config.MaxBodySize]) + "...(truncated)"
+	}
+	return string(data)
+}
+
+// redactValue 脱敏敏感字段、数据密钥 tag，以及加密配置文件的明文内容
+func (t *debugTransport) redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if isSensitiveTag(v) {
+			v["value"] = redacted
+			return v
+		}
+		for key, field := range v {
+			if t.sensitive[strings.ToLower(key)] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = t.redactValue(field)
+		}
+		if _, ok := v["content"]; ok && isEncryptedFile(v) {
+			v["content"] = redacted
+		}
+	case []any:
+		for i := range v {
+			v[i] = t.redactValue(v[i])
+		}
+	}
+	return value
+}
+
+func isSensitiveTag(tag map[string]any) bool {
+	key, _ := tag["key"].(string)
+	_, ok := tag["value"]
+	return ok && key == tagKeyDataKey
+}
+
+func isEncryptedFile(file map[string]any) bool {
+	if encrypted, _ := file["encrypted"].(bool); encrypted {
+		return true
+	}
+	tags, _ := file["tags"].([]any)
+	for _, tag := range tags {
+		tag, _ := tag.(map[string]any)
+		if key, _ := tag["key"].(string); key == tagKeyEncrypted {
+			value, _ := tag["value"].(string)
+			return value == "true"
+		}
+	}
+	return false
+}
+
+func (t *debugTransport) redactHeader(header http.Header) string {
+	redactedHeader := make(http.Header, len(header))
+	for key, values := range header {
+		if t.sensitive[strings.ToLower(key)] {
+			redactedHeader[key] = []string{redacted}
+			continue
+		}
+		redactedHeader[key] = values
+	}
+	data, _ := jsoniter.MarshalToString(redactedHeader)
+	return data
+}
+
+func (t *debugTransport) redactURL(u *url.URL) string {
+	redactedURL := *u
+	query := redactedURL.Query()
+	for key := range query {
+		if t.sensitive[strings.ToLower(key)] {
+			query.Set(key, redacted)
+		}
+	}
+	redactedURL.RawQuery = query.Encode()
+	return redactedURL.Redacted()
+}
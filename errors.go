@@ -0,0 +1,116 @@
+package polaris
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/polarismesh/specification/source/go/api/v1/model"
+	"net"
+	"net/http"
+)
+
+// PolarisError 北极星接口返回的错误
+type PolarisError struct {
+	// Code 北极星返回码
+	Code model.Code
+	// Info 北极星返回的错误信息
+	Info string
+	// StatusCode HTTP 状态码
+	StatusCode int
+	// Node 响应请求的北极星节点
+	Node string
+}
+
+func (e *PolarisError) Error() string {
+	return fmt.Sprintf("polaris: code=%d(%s) status=%d node=%s info=%s", e.Code, e.Code, e.StatusCode, e.Node, e.Info)
+}
+
+// NewError 根据响应创建 PolarisError
+func NewError(resp *resty.Response, code model.Code, info string) *PolarisError {
+	return &PolarisError{
+		Code:       code,
+		Info:       info,
+		StatusCode: resp.StatusCode(),
+		Node:       Node(resp),
+	}
+}
+
+// CheckResponse 检查北极星返回码及 HTTP 状态码，失败时返回 *PolarisError。
+// okCodes 为视为成功的返回码，默认为 ExecuteSuccess
+func CheckResponse(resp *resty.Response, code model.Code, info string, okCodes ...model.Code) error {
+	if len(okCodes) == 0 {
+		okCodes = []model.Code{model.Code_ExecuteSuccess}
+	}
+	if !resp.IsError() {
+		for _, ok := range okCodes {
+			if code == ok {
+				return nil
+			}
+		}
+	}
+	return NewError(resp, code, info)
+}
+
+// CodeResult 带北极星返回码的响应体
+type CodeResult interface {
+	GetCode() model.Code
+	GetMessage() string
+}
+
+// ParseResponse 将响应体解析到 result 并检查返回码，失败时返回 *PolarisError
+func ParseResponse(resp *resty.Response, result CodeResult, okCodes ...model.Code) error {
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(resp.Body(), result); err != nil {
+		if resp.IsError() {
+			return NewError(resp, 0, resp.String())
+		}
+		return err
+	}
+	return CheckResponse(resp, result.GetCode(), result.GetMessage(), okCodes...)
+}
+
+// Node 获取响应请求的北极星节点地址
+func Node(resp *resty.Response) string {
+	if resp == nil || resp.RawResponse == nil || resp.RawResponse.Request == nil {
+		return ""
+	}
+	return resp.RawResponse.Request.URL.Host
+}
+
+// IsNotFound 判断是否为资源不存在错误
+func IsNotFound(err error) bool {
+	var e *PolarisError
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Code == model.Code_NotFoundResource || e.Code == model.Code_NotFoundResourceConfigFile
+}
+
+// IsUnauthorized 判断是否为鉴权失败或无权限错误
+func IsUnauthorized(err error) bool {
+	var e *PolarisError
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.StatusCode == http.StatusUnauthorized || isAuthCode(e.Code) ||
+		e.Code == model.Code_NotAllowedAccess || e.Code == model.Code_AuthTokenForbidden ||
+		e.Code == model.Code_OperationRoleForbidden
+}
+
+// IsRetryable 判断错误是否可以重试，包括网络错误、限流及服务端异常
+func IsRetryable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var e *PolarisError
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Code {
+	case model.Code_ExecuteException, model.Code_StoreLayerException,
+		model.Code_InstanceTooManyRequests, model.Code_IPRateLimit, model.Code_APIRateLimit:
+		return true
+	}
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
@@ -52,6 +52,7 @@ func main() {
 
 	log.Infoln("创建配置文件")
 	// 创建并发布配置文件
+	configClient := configfiles.NewClient(client)
 	go func() {
 		for i := 0; i < 30; i++ {
 			ulidStr := ulid.Make().String()
@@ -59,7 +60,7 @@ func main() {
 			if err != nil {
 				return
 			}
-			_, err = configfiles.CreateAndPub(&configfiles.ConfigFile{
+			_, err = configClient.CreateAndPub(&configfiles.ConfigFile{
 				// 每次传不同的 ReleaseName 才会自动发布，重复相同的 ReleaseName 配置文件的状态为 "编辑待发布"
 				ReleaseName:        ulidStr,
 				ReleaseDescription: "update blacklist",
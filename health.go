@@ -0,0 +1,141 @@
+package polaris
+
+import (
+	"context"
+	"errors"
+	"github.com/nxsre/polaris-go/log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultFailureThreshold = 3
+	defaultEjectDuration    = 30 * time.Second
+	defaultProbePath        = "/"
+	defaultProbeTimeout     = 5 * time.Second
+)
+
+// HealthCheckConfig 北极星节点健康检查配置。
+// 请求失败会降低节点有效权重，连续失败达到阈值后摘除节点，冷却期过后重新参与选择；
+// 开启主动探测后，被摘除的节点探测成功即可提前恢复
+type HealthCheckConfig struct {
+	// FailureThreshold 连续失败多少次后摘除节点，默认 3
+	FailureThreshold int
+	// EjectDuration 节点被摘除的冷却时长，默认 30s
+	EjectDuration time.Duration
+	// ProbeInterval 主动探测被摘除节点的间隔，为 0 时不主动探测
+	ProbeInterval time.Duration
+	// ProbePath 主动探测请求的路径，默认 "/"
+	ProbePath string
+}
+
+func (c HealthCheckConfig) withDefaults() HealthCheckConfig {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = defaultFailureThreshold
+	}
+	if c.EjectDuration <= 0 {
+		c.EjectDuration = defaultEjectDuration
+	}
+	if c.ProbePath == "" {
+		c.ProbePath = defaultProbePath
+	}
+	return c
+}
+
+// isNodeFailure 网络错误及网关返回的 502、503、504 视为节点故障。北极星将 ExecuteException、
+// StoreLayerException 等返回码映射为 HTTP 500，此时节点能够应答，不视为故障
+func isNodeFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isCallerCanceled 请求因调用方取消或超时而失败，如调用方的 deadline、WithTimeout 或 SDK 的 context 结束长轮询，
+// 不代表节点故障
+func isCallerCanceled(req *http.Request, err error) bool {
+	return err != nil && (req.Context().Err() != nil || errors.Is(err, context.Canceled))
+}
+
+// report 根据请求结果更新节点的健康状态
+func (r *WeightRoundRobinBalance) report(node *WeightNode, ok bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if ok {
+		if node.failures >= r.health.FailureThreshold {
+			log.Infof("polaris node %s recovered", node.host)
+		}
+		node.failures = 0
+		node.ejectedUntil = time.Time{}
+		return
+	}
+
+	node.failures++
+	if node.effectiveWeight > 0 {
+		node.effectiveWeight--
+	}
+	// 冷却期后的首个请求仍失败时再次摘除
+	if node.failures >= r.health.FailureThreshold {
+		node.ejectedUntil = time.Now().Add(r.health.EjectDuration)
+		log.Warnf("polaris node %s ejected for %s after %d failures", node.host, r.health.EjectDuration, node.failures)
+	}
+}
+
+// ejectedNodes 返回当前处于摘除状态的节点
+func (r *WeightRoundRobinBalance) ejectedNodes() []*WeightNode {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var nodes []*WeightNode
+	for _, node := range r.rss {
+		if node.failures >= r.health.FailureThreshold {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// probe 周期性主动探测被摘除的节点，探测成功则恢复节点
+func (r *WeightRoundRobinBalance) probe(ctx context.Context) {
+	ticker := time.NewTicker(r.health.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, node := range r.ejectedNodes() {
+				if r.probeNode(ctx, node) {
+					r.report(node, true)
+				}
+			}
+		}
+	}
+}
+
+func (r *WeightRoundRobinBalance) probeNode(ctx context.Context, node *WeightNode) bool {
+	timeout := r.health.ProbeInterval
+	if timeout > defaultProbeTimeout {
+		timeout = defaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, node.scheme+"://"+node.addr()+r.health.ProbePath, nil)
+	if err != nil {
+		return false
+	}
+	req.Host = node.authority()
+	resp, err := node.transport.RoundTrip(req)
+	if err != nil {
+		log.Debugf("probe polaris node %s: %v", node.host, err)
+		return false
+	}
+	resp.Body.Close()
+	return !isNodeFailure(resp, nil)
+}
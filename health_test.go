@@ -0,0 +1,70 @@
+package polaris
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNodeHealthReport(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		w.WriteHeader(int(status.Load()))
+	})
+	s1, s2 := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer s1.Close()
+	defer s2.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	transport, err := newBalancedTransport(ctx, nil, []string{s1.URL, s2.URL}, &options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip := func(ctx context.Context, path string) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://polaris"+path, nil)
+		if resp, err := transport.RoundTrip(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	// 调用方超时及取消不是节点故障
+	for i := 0; i < 2*defaultFailureThreshold; i++ {
+		reqCtx, reqCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		roundTrip(reqCtx, "/slow")
+		reqCancel()
+		reqCtx, reqCancel = context.WithCancel(context.Background())
+		reqCancel()
+		roundTrip(reqCtx, "/")
+	}
+	if nodes := transport.balance.ejectedNodes(); len(nodes) != 0 {
+		t.Errorf("ejected %d nodes after caller cancellations, want 0", len(nodes))
+	}
+
+	// 北极星业务异常映射的 HTTP 500 说明节点能够应答
+	status.Store(http.StatusInternalServerError)
+	for i := 0; i < 2*defaultFailureThreshold; i++ {
+		roundTrip(context.Background(), "/")
+	}
+	if nodes := transport.balance.ejectedNodes(); len(nodes) != 0 {
+		t.Errorf("ejected %d nodes after HTTP 500, want 0", len(nodes))
+	}
+
+	status.Store(http.StatusBadGateway)
+	for i := 0; i < 2*defaultFailureThreshold; i++ {
+		roundTrip(context.Background(), "/")
+	}
+	if nodes := transport.balance.ejectedNodes(); len(nodes) != 2 {
+		t.Errorf("ejected %d nodes after HTTP 502, want 2", len(nodes))
+	}
+}
@@ -0,0 +1,80 @@
+package polaris
+
+import (
+	"github.com/go-resty/resty/v2"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// 长轮询结果
+const (
+	LongPollNoChange = "no_change"
+	LongPollChanged  = "changed"
+	LongPollError    = "error"
+)
+
+// Metrics 客户端指标采集接口，实现需要支持并发调用
+type Metrics interface {
+	// ObserveRequest 记录一次发往北极星节点的请求，status 为 HTTP 状态码，请求出错时为 0
+	ObserveRequest(endpoint, node string, status int, duration time.Duration)
+	// IncRetry 记录一次请求重试
+	IncRetry(endpoint string)
+	// IncLoginRefresh 记录一次 token 刷新
+	IncLoginRefresh(success bool)
+	// AddWatchedFiles 增减监听中的配置文件数量
+	AddWatchedFiles(delta int)
+	// ObserveLongPoll 记录一次配置文件监听长轮询的结果
+	ObserveLongPoll(outcome string)
+	// ObserveEventFanout 记录一次变更事件分发给所有监听器的耗时
+	ObserveEventFanout(duration time.Duration)
+	// ObserveRateLimitWait 记录一次请求等待客户端限流的耗时，kind 为 RequestKind
+	ObserveRateLimitWait(kind string, duration time.Duration)
+}
+
+// NopMetrics 不采集任何指标
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveRequest(string, string, int, time.Duration) {}
+func (NopMetrics) IncRetry(string)                                   {}
+func (NopMetrics) IncLoginRefresh(bool)                              {}
+func (NopMetrics) AddWatchedFiles(int)                               {}
+func (NopMetrics) ObserveLongPoll(string)                            {}
+func (NopMetrics) ObserveEventFanout(time.Duration)                  {}
+func (NopMetrics) ObserveRateLimitWait(string, time.Duration)        {}
+
+// Metrics 获取客户端的指标采集接口
+func (p *Polaris) Metrics() Metrics {
+	return p.metrics
+}
+
+// metricsTransport 记录每次发往北极星节点的请求
+type metricsTransport struct {
+	metrics Metrics
+	next    http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	t.metrics.ObserveRequest(req.URL.Path, req.URL.Host, status, time.Since(start))
+	return resp, err
+}
+
+// retryHook 记录 resty 的请求重试
+func retryHook(metrics Metrics) resty.OnRetryFunc {
+	return func(resp *resty.Response, _ error) {
+		if resp == nil || resp.Request == nil {
+			return
+		}
+		endpoint := resp.Request.URL
+		if u, err := url.Parse(resp.Request.URL); err == nil {
+			endpoint = u.Path
+		}
+		metrics.IncRetry(endpoint)
+	}
+}
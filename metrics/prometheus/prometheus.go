@@ -0,0 +1,119 @@
+// Package prometheus 基于 Prometheus 实现 polaris.Metrics
+package prometheus
+
+import (
+	"github.com/nxsre/polaris-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+	"time"
+)
+
+const namespace = "polaris_client"
+
+// Metrics 基于 Prometheus 的 polaris.Metrics 实现
+type Metrics struct {
+	requests     *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	retries      *prometheus.CounterVec
+	loginRefresh *prometheus.CounterVec
+	watchedFiles prometheus.Gauge
+	longPolls    *prometheus.CounterVec
+	eventFanout  prometheus.Histogram
+	rateLimit    *prometheus.HistogramVec
+}
+
+var _ polaris.Metrics = (*Metrics)(nil)
+
+// New 创建指标并注册到 reg，reg 为 nil 时使用 prometheus.DefaultRegisterer
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Requests sent to polaris servers, by endpoint, node and HTTP status (0 on transport error).",
+		}, []string{"endpoint", "node", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests sent to polaris servers.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint", "node"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Retried requests, by endpoint.",
+		}, []string{"endpoint"}),
+		loginRefresh: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "login_refresh_total",
+			Help:      "Token refreshes, by result.",
+		}, []string{"result"}),
+		watchedFiles: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "watched_files",
+			Help:      "Config files currently being watched.",
+		}),
+		longPolls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "long_polls_total",
+			Help:      "Config file watch long-poll results, by outcome.",
+		}, []string{"outcome"}),
+		eventFanout: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "event_fanout_duration_seconds",
+			Help:      "Time spent delivering a change event to all listeners.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}),
+		rateLimit: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_wait_seconds",
+			Help:      "Time requests spent waiting for the client-side rate limiter, by request kind.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"kind"}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.requests, m.latency, m.retries, m.loginRefresh, m.watchedFiles, m.longPolls, m.eventFanout, m.rateLimit,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) ObserveRequest(endpoint, node string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(endpoint, node, strconv.Itoa(status)).Inc()
+	m.latency.WithLabelValues(endpoint, node).Observe(duration.Seconds())
+}
+
+func (m *Metrics) IncRetry(endpoint string) {
+	m.retries.WithLabelValues(endpoint).Inc()
+}
+
+func (m *Metrics) IncLoginRefresh(success bool) {
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	m.loginRefresh.WithLabelValues(result).Inc()
+}
+
+func (m *Metrics) AddWatchedFiles(delta int) {
+	m.watchedFiles.Add(float64(delta))
+}
+
+func (m *Metrics) ObserveLongPoll(outcome string) {
+	m.longPolls.WithLabelValues(outcome).Inc()
+}
+
+func (m *Metrics) ObserveEventFanout(duration time.Duration) {
+	m.eventFanout.Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveRateLimitWait(kind string, duration time.Duration) {
+	m.rateLimit.WithLabelValues(kind).Observe(duration.Seconds())
+}
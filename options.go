@@ -0,0 +1,238 @@
+package polaris
+
+import (
+	"context"
+	"github.com/nxsre/polaris-go/log"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultBaseURL 默认访问地址，host 部分会被负载均衡替换为实际的北极星节点
+	DefaultBaseURL = "http://polaris.com"
+
+	defaultRetryCount    = 3
+	defaultRetryWaitTime = 3 * time.Second
+)
+
+// Option 创建 Polaris 客户端的可选配置
+type Option func(*options)
+
+type options struct {
+	credentials CredentialProvider
+
+	timeout          time.Duration
+	retryCount       int
+	retryWaitTime    time.Duration
+	retryMaxWaitTime time.Duration
+
+	httpClient *http.Client
+	transport  http.RoundTripper
+	tls        *TLSConfig
+	health     HealthCheckConfig
+
+	dnsRefreshInterval time.Duration
+	strategy           Strategy
+
+	metrics Metrics
+
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+
+	rateLimits map[RequestKind]RateLimit
+
+	debug DebugConfig
+
+	logger               log.Logger
+	userAgent            string
+	baseURL              string
+	tokenRefreshInterval time.Duration
+}
+
+func defaultOptions() *options {
+	return &options{
+		retryCount:    defaultRetryCount,
+		retryWaitTime: defaultRetryWaitTime,
+		logger:        log.With("app", "polaris"),
+		metrics:       NopMetrics{},
+		baseURL:       DefaultBaseURL,
+	}
+}
+
+// WithCredentials 使用账号密码登录，token 失效时会用它重新登录
+func WithCredentials(username, password string) Option {
+	return WithCredentialProvider(StaticCredentials(username, password))
+}
+
+// WithToken 使用预先签发的 token，不再调用登录接口
+func WithToken(token string) Option {
+	return WithCredentialProvider(TokenCredentials(token))
+}
+
+// WithCredentialProvider 设置登录凭据来源，未设置时不登录，适用于未开启鉴权的北极星
+func WithCredentialProvider(provider CredentialProvider) Option {
+	return func(o *options) {
+		o.credentials = provider
+	}
+}
+
+// WithTimeout 设置单次请求超时，不作用于配置文件监听的长轮询请求
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetry 设置请求失败的重试次数以及重试等待时间
+func WithRetry(count int, waitTime, maxWaitTime time.Duration) Option {
+	return func(o *options) {
+		o.retryCount = count
+		o.retryWaitTime = waitTime
+		o.retryMaxWaitTime = maxWaitTime
+	}
+}
+
+// WithHTTPClient 使用自定义的 http.Client，客户端会被复制后再包装，不会修改传入的对象
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithTransport 使用自定义的 http.RoundTripper 发送请求
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithTLS 设置访问 https 北极星节点的 TLS 及双向认证配置，
+// 自定义 transport 时要求其为 *http.Transport
+func WithTLS(config *TLSConfig) Option {
+	return func(o *options) {
+		o.tls = config
+	}
+}
+
+// WithHealthCheck 设置北极星节点的故障摘除及主动探测配置
+func WithHealthCheck(config HealthCheckConfig) Option {
+	return func(o *options) {
+		o.health = config
+	}
+}
+
+// WithDNSRefresh 按 interval 周期性重新解析北极星节点的主机名，跟随 DNS 记录增删节点
+func WithDNSRefresh(interval time.Duration) Option {
+	return func(o *options) {
+		o.dnsRefreshInterval = interval
+	}
+}
+
+// WithStrategy 设置北极星节点选择策略，默认为平滑加权轮询
+func WithStrategy(strategy Strategy) Option {
+	return func(o *options) {
+		o.strategy = strategy
+	}
+}
+
+// WithMetrics 设置指标采集，默认不采集
+func WithMetrics(metrics Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+// WithTracerProvider 开启 OpenTelemetry tracing，为 SDK 调用创建 span 并向请求头注入 trace context
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = provider
+	}
+}
+
+// WithPropagator 设置注入 trace context 使用的 propagator，默认使用 otel 全局 propagator
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = propagator
+	}
+}
+
+// WithRateLimit 为 kind 类别的请求设置客户端限流，超出额度的请求会等待，
+// 等待期间请求的 context 结束则返回错误
+func WithRateLimit(kind RequestKind, limit RateLimit) Option {
+	return func(o *options) {
+		if o.rateLimits == nil {
+			o.rateLimits = map[RequestKind]RateLimit{}
+		}
+		o.rateLimits[kind] = limit
+	}
+}
+
+// WithDebug 设置请求调试日志，日志中的 token、密码、数据密钥等敏感信息会被脱敏
+func WithDebug(config DebugConfig) Option {
+	return func(o *options) {
+		o.debug = config
+	}
+}
+
+// WithLogger 设置客户端日志
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithUserAgent 设置请求的 User-Agent
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithBaseURL 设置访问地址，可指定 scheme 和路径前缀。
+// 未指定北极星节点列表时直接访问该地址，可用于 VIP 或网关之后的北极星集群
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithTokenRefresh 按 interval 周期性主动刷新 token，客户端 Close 后停止
+func WithTokenRefresh(interval time.Duration) Option {
+	return func(o *options) {
+		o.tokenRefreshInterval = interval
+	}
+}
+
+// timeoutTransport 为普通请求设置超时，长轮询请求由调用方的 context 控制
+type timeoutTransport struct {
+	timeout time.Duration
+	next    http.RoundTripper
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 || isLongPoll(req) {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// 响应体读取完成后再释放 context
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
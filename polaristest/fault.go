@@ -0,0 +1,85 @@
+package polaristest
+
+import (
+	"github.com/polarismesh/specification/source/go/api/v1/model"
+	"net/http"
+)
+
+// Fault 注入到请求的故障
+type Fault struct {
+	// Code 返回的北极星返回码，默认为 ExecuteException
+	Code model.Code
+	// Info 返回的错误信息
+	Info string
+	// Status 返回的 HTTP 状态码，默认为返回码的前三位
+	Status int
+	// Drop 返回不完整的响应后断开连接，客户端收到传输错误
+	Drop bool
+}
+
+// injectedFault 注入的故障及剩余次数，remaining 小于等于 0 时不限次数
+type injectedFault struct {
+	fault     Fault
+	remaining int
+}
+
+// InjectFault 使 path 接口之后的 times 个请求返回 fault，times 小于等于 0 时一直返回直到 ClearFaults。
+// 同一接口注入的多个故障按注入顺序依次生效
+func (s *Server) InjectFault(path string, times int, fault Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults[path] = append(s.faults[path], &injectedFault{fault: fault, remaining: times})
+}
+
+// ClearFaults 清除所有注入的故障
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = map[string][]*injectedFault{}
+}
+
+// nextFault 取出 path 接口的下一个故障，调用方需持有锁
+func (s *Server) nextFault(path string) (Fault, bool) {
+	faults := s.faults[path]
+	if len(faults) == 0 {
+		return Fault{}, false
+	}
+	injected := faults[0]
+	if injected.remaining > 0 {
+		injected.remaining--
+		if injected.remaining == 0 {
+			s.faults[path] = faults[1:]
+		}
+	}
+	return injected.fault, true
+}
+
+func (f Fault) serve(w http.ResponseWriter, r *http.Request) {
+	if f.Drop {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				// 直接断开复用的连接时 http.Transport 会自动重试，写入部分响应使客户端确定收到错误
+				conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 1024\r\n\r\n"))
+				conn.Close()
+				return
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+
+	code := f.Code
+	if code == 0 {
+		code = model.Code_ExecuteException
+	}
+	info := f.Info
+	if info == "" {
+		info = code.String()
+	}
+	status := f.Status
+	if status == 0 {
+		status = httpStatus(code)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"code": code, "info": info})
+}
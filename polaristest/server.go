@@ -0,0 +1,509 @@
+// Package polaristest 提供进程内的北极星模拟服务，用于在没有北极星集群时测试使用 SDK 的代码
+package polaristest
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nxsre/polaris-go"
+	"github.com/nxsre/polaris-go/api/configfiles"
+	"github.com/nxsre/polaris-go/crypto"
+	"github.com/nxsre/polaris-go/sdk"
+	"github.com/polarismesh/specification/source/go/api/v1/model"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// 模拟服务实现的接口
+const (
+	PathLogin                  = "/core/v1/user/login"
+	PathGetConfigFile          = "/config/v1/GetConfigFile"
+	PathConfigFileMetadataList = "/config/v1/GetConfigFileMetadataList"
+	PathWatchConfigFile        = "/config/v1/WatchConfigFile"
+	PathCreateAndPub           = "/config/v1/configfiles/createandpub"
+	PathConfigFiles            = "/config/v1/configfiles"
+
+	defaultWatchTimeout = 30 * time.Second
+)
+
+// Option 模拟服务的可选配置
+type Option func(*Server)
+
+// WithCredentials 开启鉴权，只有使用该账号密码登录获取的 token 才能访问配置接口
+func WithCredentials(username, password string) Option {
+	return func(s *Server) {
+		s.username, s.password = username, password
+	}
+}
+
+// WithTokenTTL 设置登录签发的 token 有效期，默认不过期
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.tokenTTL = ttl
+	}
+}
+
+// WithWatchTimeout 设置监听长轮询无变更时的返回时间，默认 30s
+func WithWatchTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.watchTimeout = timeout
+	}
+}
+
+type fileKey struct {
+	namespace string
+	group     string
+	name      string
+}
+
+// file 配置文件的当前版本，deleted 为删除后保留的版本，用于通知监听方
+type file struct {
+	config  sdk.ConfigFile
+	version uint64
+	deleted bool
+}
+
+// Server 基于 httptest 的北极星模拟服务，Close 后停止
+type Server struct {
+	// URL 模拟服务的访问地址，可直接作为北极星节点传入 polaris.NewPolaris
+	URL string
+
+	server       *httptest.Server
+	username     string
+	password     string
+	tokenTTL     time.Duration
+	watchTimeout time.Duration
+
+	lock     sync.Mutex
+	files    map[fileKey]*file
+	version  uint64
+	changed  chan struct{}
+	tokens   map[string]time.Time
+	tokenSeq int
+	latency  time.Duration
+	faults   map[string][]*injectedFault
+	requests map[string]int
+	done     chan struct{}
+}
+
+// NewServer 启动模拟服务
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		watchTimeout: defaultWatchTimeout,
+		files:        map[fileKey]*file{},
+		changed:      make(chan struct{}),
+		tokens:       map[string]time.Time{},
+		faults:       map[string][]*injectedFault{},
+		requests:     map[string]int{},
+		done:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathLogin, s.login)
+	mux.HandleFunc(PathGetConfigFile, s.authorized(s.getConfigFile))
+	mux.HandleFunc(PathConfigFileMetadataList, s.authorized(s.getConfigFileMetadataList))
+	mux.HandleFunc(PathWatchConfigFile, s.authorized(s.watchConfigFile))
+	mux.HandleFunc(PathCreateAndPub, s.authorized(s.createAndPub))
+	mux.HandleFunc(PathConfigFiles, s.authorized(s.deleteConfigFile))
+	s.server = httptest.NewServer(s.intercept(mux))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close 结束挂起的长轮询并关闭模拟服务
+func (s *Server) Close() {
+	s.lock.Lock()
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	s.lock.Unlock()
+	s.server.Close()
+}
+
+// Client 创建访问模拟服务的北极星客户端，开启鉴权时使用 WithCredentials 配置的账号密码登录
+func (s *Server) Client(opts ...polaris.Option) (*polaris.Polaris, error) {
+	if s.username != "" {
+		opts = append([]polaris.Option{polaris.WithCredentials(s.username, s.password)}, opts...)
+	}
+	return polaris.NewPolarisWithOptions([]string{s.URL}, opts...)
+}
+
+// Publish 直接发布配置文件，返回发布后的版本号
+func (s *Server) Publish(namespace, group, name, content string, tags ...sdk.ConfigFileTag) uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.publish(namespace, group, name, content, "", tags)
+}
+
+func (s *Server) publish(namespace, group, name, content, format string, tags []sdk.ConfigFileTag) uint64 {
+	s.version++
+	encrypted := false
+	for _, tag := range tags {
+		if tag.Key == sdk.ConfigFileTagKeyUseEncrypted {
+			encrypted = tag.Value == "true"
+		}
+	}
+	s.files[fileKey{namespace, group, name}] = &file{
+		config: sdk.ConfigFile{
+			Namespace: namespace,
+			Group:     group,
+			FileName:  name,
+			Name:      name,
+			Content:   content,
+			Tags:      append([]sdk.ConfigFileTag(nil), tags...),
+			Format:    format,
+			Version:   strconv.FormatUint(s.version, 10),
+			Md5:       fmt.Sprintf("%x", md5.Sum([]byte(content))),
+			Encrypted: encrypted,
+		},
+		version: s.version,
+	}
+	s.notify()
+	return s.version
+}
+
+// Delete 直接删除配置文件，文件不存在时返回 false
+func (s *Server) Delete(namespace, group, name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.delete(namespace, group, name)
+}
+
+func (s *Server) delete(namespace, group, name string) bool {
+	f, ok := s.files[fileKey{namespace, group, name}]
+	if !ok || f.deleted {
+		return false
+	}
+	s.version++
+	f.version = s.version
+	f.deleted = true
+	s.notify()
+	return true
+}
+
+// File 获取配置文件的当前内容
+func (s *Server) File(namespace, group, name string) (sdk.ConfigFile, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	f, ok := s.files[fileKey{namespace, group, name}]
+	if !ok || f.deleted {
+		return sdk.ConfigFile{}, false
+	}
+	return f.config, true
+}
+
+// SetLatency 为之后的每个请求增加 latency 的处理延迟
+func (s *Server) SetLatency(latency time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.latency = latency
+}
+
+// ExpireTokens 使已签发的 token 全部失效，客户端需要重新登录
+func (s *Server) ExpireTokens() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tokens = map[string]time.Time{}
+}
+
+// Requests 获取 path 接口收到的请求数，包括被注入故障的请求
+func (s *Server) Requests(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[path]
+}
+
+// notify 唤醒等待中的长轮询，调用方需持有锁
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// intercept 统计请求数，并按配置注入延迟及故障
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		s.requests[r.URL.Path]++
+		latency := s.latency
+		fault, faulted := s.nextFault(r.URL.Path)
+		s.lock.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			case <-s.done:
+				return
+			}
+		}
+		if faulted {
+			fault.serve(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCode(w, model.Code_ParseException, err.Error())
+		return
+	}
+	if s.username != "" && (req.Name != s.username || req.Password != s.password) {
+		writeCode(w, model.Code_NotAllowedAccess, "invalid username or password")
+		return
+	}
+
+	s.lock.Lock()
+	s.tokenSeq++
+	token := fmt.Sprintf("polaristest-token-%d", s.tokenSeq)
+	expire := time.Time{}
+	if s.tokenTTL > 0 {
+		expire = time.Now().Add(s.tokenTTL)
+	}
+	s.tokens[token] = expire
+	s.lock.Unlock()
+
+	writeJSON(w, model.Code_ExecuteSuccess, map[string]any{
+		"code":          model.Code_ExecuteSuccess,
+		"info":          "execute success",
+		"loginResponse": map[string]string{"token": token, "name": req.Name},
+	})
+}
+
+// authorized 开启鉴权时校验请求的 token
+func (s *Server) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.username == "" {
+			handler(w, r)
+			return
+		}
+		token := r.Header.Get(polaris.TokenHeader)
+		if token == "" {
+			writeCode(w, model.Code_EmptyAutToken, "empty auth token")
+			return
+		}
+		s.lock.Lock()
+		expire, ok := s.tokens[token]
+		s.lock.Unlock()
+		if !ok || (!expire.IsZero() && time.Now().After(expire)) {
+			writeCode(w, model.Code_TokenNotExisted, "token not existed")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) getConfigFile(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	config, ok := s.File(query.Get("namespace"), query.Get("group"), query.Get("fileName"))
+	if !ok {
+		writeCode(w, model.Code_NotFoundResource, "config file not found")
+		return
+	}
+	if publicKey := query.Get("publicKey"); publicKey != "" {
+		var err error
+		if config.Tags, err = encryptDataKey(config.Tags, publicKey); err != nil {
+			writeCode(w, model.Code_InvalidParameter, err.Error())
+			return
+		}
+	}
+	writeJSON(w, model.Code_ExecuteSuccess, map[string]any{
+		"code":       model.Code_ExecuteSuccess,
+		"info":       "execute success",
+		"configFile": config,
+	})
+}
+
+// encryptDataKey 与北极星一致，客户端发送公钥时使用公钥加密返回的数据密钥
+func encryptDataKey(tags []sdk.ConfigFileTag, publicKey string) ([]sdk.ConfigFileTag, error) {
+	pub, err := crypto.ParseRSAPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := make([]sdk.ConfigFileTag, len(tags))
+	for i, tag := range tags {
+		encrypted[i] = tag
+		if tag.Key != sdk.ConfigFileTagKeyDataKey {
+			continue
+		}
+		dataKey, err := base64.StdEncoding.DecodeString(tag.Value)
+		if err != nil {
+			return nil, err
+		}
+		cipherKey, err := crypto.RSAEncrypt(dataKey, pub)
+		if err != nil {
+			return nil, err
+		}
+		encrypted[i].Value = base64.StdEncoding.EncodeToString(cipherKey)
+	}
+	return encrypted, nil
+}
+
+func (s *Server) getConfigFileMetadataList(w http.ResponseWriter, r *http.Request) {
+	var req sdk.ConfigFileMetadataListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCode(w, model.Code_ParseException, err.Error())
+		return
+	}
+
+	s.lock.Lock()
+	infos := []sdk.ConfigFile{}
+	var revision uint64
+	for key, f := range s.files {
+		if key.namespace != req.ConfigFileGroup.Namespace || key.group != req.ConfigFileGroup.Name {
+			continue
+		}
+		if f.version > revision {
+			revision = f.version
+		}
+		if f.deleted {
+			continue
+		}
+		info := f.config
+		info.Content = ""
+		infos = append(infos, info)
+	}
+	s.lock.Unlock()
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].FileName < infos[j].FileName
+	})
+
+	writeJSON(w, model.Code_ExecuteSuccess, &sdk.ConfigFileMetadataListResult{
+		Code:            int(model.Code_ExecuteSuccess),
+		Info:            "execute success",
+		Revision:        strconv.FormatUint(revision, 10),
+		Namespace:       req.ConfigFileGroup.Namespace,
+		Group:           req.ConfigFileGroup.Name,
+		ConfigFileInfos: infos,
+	})
+}
+
+// watchConfigFile 有文件版本与请求中的版本不一致时立即返回该文件，
+// 否则等待变更，超过 watchTimeout 仍无变更时返回 DataNoChange
+func (s *Server) watchConfigFile(w http.ResponseWriter, r *http.Request) {
+	var req sdk.WatchFilesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCode(w, model.Code_ParseException, err.Error())
+		return
+	}
+
+	timeout := time.NewTimer(s.watchTimeout)
+	defer timeout.Stop()
+	for {
+		s.lock.Lock()
+		changed, ok := s.changedFile(req.WatchFiles)
+		wait := s.changed
+		s.lock.Unlock()
+		if ok {
+			writeJSON(w, model.Code_ExecuteSuccess, map[string]any{
+				"code":       model.Code_ExecuteSuccess,
+				"info":       "execute success",
+				"configFile": changed,
+			})
+			return
+		}
+
+		select {
+		case <-wait:
+		case <-timeout.C:
+			writeCode(w, model.Code_DataNoChange, "data no change")
+			return
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			// 模拟服务关闭时断开连接
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// changedFile 查找版本发生变化的监听文件，调用方需持有锁
+func (s *Server) changedFile(watchFiles []sdk.WatchFile) (sdk.ConfigFile, bool) {
+	for _, watchFile := range watchFiles {
+		f, ok := s.files[fileKey{watchFile.Namespace, watchFile.Group, watchFile.FileName}]
+		if !ok || f.version == watchFile.Version {
+			continue
+		}
+		return sdk.ConfigFile{
+			Namespace: watchFile.Namespace,
+			Group:     watchFile.Group,
+			FileName:  watchFile.FileName,
+			Name:      watchFile.FileName,
+			Version:   strconv.FormatUint(f.version, 10),
+		}, true
+	}
+	return sdk.ConfigFile{}, false
+}
+
+func (s *Server) createAndPub(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeCode(w, model.Code_InvalidParameter, "method not allowed")
+		return
+	}
+	var req configfiles.ConfigFile
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCode(w, model.Code_ParseException, err.Error())
+		return
+	}
+	if req.Namespace == "" || req.Group == "" || req.FileName == "" {
+		writeCode(w, model.Code_InvalidParameter, "namespace, group and file_name are required")
+		return
+	}
+
+	s.lock.Lock()
+	s.publish(req.Namespace, req.Group, req.FileName, req.Content, req.Format, req.Tags)
+	config := s.files[fileKey{req.Namespace, req.Group, req.FileName}].config
+	s.lock.Unlock()
+
+	writeJSON(w, model.Code_ExecuteSuccess, map[string]any{
+		"code":       model.Code_ExecuteSuccess,
+		"info":       "execute success",
+		"configFile": config,
+	})
+}
+
+func (s *Server) deleteConfigFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeCode(w, model.Code_InvalidParameter, "method not allowed")
+		return
+	}
+	query := r.URL.Query()
+	if !s.Delete(query.Get("namespace"), query.Get("group"), query.Get("name")) {
+		writeCode(w, model.Code_NotFoundResource, "config file not found")
+		return
+	}
+	writeCode(w, model.Code_ExecuteSuccess, "execute success")
+}
+
+// httpStatus 北极星 HTTP 状态码为返回码的前三位
+func httpStatus(code model.Code) int {
+	return int(code) / 1000
+}
+
+func writeCode(w http.ResponseWriter, code model.Code, info string) {
+	writeJSON(w, code, map[string]any{"code": code, "info": info})
+}
+
+func writeJSON(w http.ResponseWriter, code model.Code, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(code))
+	json.NewEncoder(w).Encode(body)
+}
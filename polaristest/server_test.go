@@ -0,0 +1,313 @@
+package polaristest
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/nxsre/polaris-go"
+	"github.com/nxsre/polaris-go/api/configfiles"
+	"github.com/nxsre/polaris-go/crypto"
+	"github.com/nxsre/polaris-go/sdk"
+	polarismodel "github.com/polarismesh/polaris-go/pkg/model"
+	"github.com/polarismesh/specification/source/go/api/v1/model"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newSDK(t *testing.T, s *Server, opts ...polaris.Option) *sdk.SDK {
+	t.Helper()
+	client, err := s.Client(append([]polaris.Option{polaris.WithRetry(0, 0, 0)}, opts...)...)
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		client.Close()
+	})
+	return sdk.NewSDK(ctx, client)
+}
+
+func getConfigFile(s *Server, namespace, group, name string) (*http.Response, error) {
+	query := url.Values{"namespace": {namespace}, "group": {group}, "fileName": {name}}
+	return http.Get(s.URL + PathGetConfigFile + "?" + query.Encode())
+}
+
+func TestLogin(t *testing.T) {
+	s := NewServer(WithCredentials("polaris", "secret"))
+	defer s.Close()
+
+	client := newSDK(t, s)
+	if got := s.Requests(PathLogin); got != 1 {
+		t.Fatalf("login requests = %d, want 1", got)
+	}
+	s.Publish("ns", "g", "a.txt", "v1")
+	if _, err := client.GetConfigFile("ns", "g", "a.txt"); err != nil {
+		t.Fatalf("GetConfigFile() error = %v", err)
+	}
+
+	if _, err := polaris.NewPolarisWithOptions([]string{s.URL}, polaris.WithCredentials("polaris", "wrong")); !polaris.IsUnauthorized(err) {
+		t.Errorf("login with wrong password error = %v, want unauthorized", err)
+	}
+
+	// 未携带 token 的请求被拒绝
+	resp, err := getConfigFile(s, "ns", "g", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request without token status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	s := NewServer(WithCredentials("polaris", "secret"), WithTokenTTL(100*time.Millisecond))
+	defer s.Close()
+	s.Publish("ns", "g", "a.txt", "v1")
+	client := newSDK(t, s)
+
+	time.Sleep(150 * time.Millisecond)
+	if _, err := client.GetConfigFile("ns", "g", "a.txt"); err != nil {
+		t.Fatalf("GetConfigFile() after token ttl error = %v", err)
+	}
+	if got := s.Requests(PathLogin); got != 2 {
+		t.Errorf("login requests after token ttl = %d, want 2", got)
+	}
+
+	s.ExpireTokens()
+	if _, err := client.GetConfigFile("ns", "g", "a.txt"); err != nil {
+		t.Fatalf("GetConfigFile() after ExpireTokens error = %v", err)
+	}
+	if got := s.Requests(PathLogin); got != 3 {
+		t.Errorf("login requests after ExpireTokens = %d, want 3", got)
+	}
+}
+
+func TestPublishAndDelete(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := newSDK(t, s)
+
+	v1 := s.Publish("ns", "g", "a.txt", "v1")
+	v2 := s.Publish("ns", "g", "b.txt", "v1")
+	v3 := s.Publish("ns", "g", "a.txt", "v2")
+	if !(v1 < v2 && v2 < v3) {
+		t.Fatalf("Publish() versions = %d, %d, %d, want increasing", v1, v2, v3)
+	}
+	result, err := client.GetConfigFile("ns", "g", "a.txt")
+	if err != nil {
+		t.Fatalf("GetConfigFile() error = %v", err)
+	}
+	if file := result.GetConfigFile(); file.GetVersion() != v3 || file.GetSourceContent() != "v2" {
+		t.Errorf("GetConfigFile() = version %d content %q, want version %d content %q", file.GetVersion(), file.GetSourceContent(), v3, "v2")
+	}
+
+	if !s.Delete("ns", "g", "a.txt") {
+		t.Fatal("Delete() = false, want true")
+	}
+	if s.Delete("ns", "g", "a.txt") {
+		t.Error("Delete() deleted file = true, want false")
+	}
+	if _, ok := s.File("ns", "g", "a.txt"); ok {
+		t.Error("File() deleted file found")
+	}
+	if _, err := client.GetConfigFile("ns", "g", "a.txt"); !polaris.IsNotFound(err) {
+		t.Errorf("GetConfigFile() deleted file error = %v, want not found", err)
+	}
+	if v4 := s.Publish("ns", "g", "a.txt", "v3"); v4 <= v3+1 {
+		t.Errorf("Publish() after delete version = %d, want greater than %d", v4, v3+1)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := NewServer(WithWatchTimeout(100 * time.Millisecond))
+	defer s.Close()
+	client := newSDK(t, s)
+	s.Publish("ns", "g", "a.txt", "v1")
+
+	watcher, err := client.WatchConfigFiles("ns", "g", "a.txt", "b.txt")
+	if err != nil {
+		t.Fatalf("WatchConfigFiles() error = %v", err)
+	}
+	events := watcher.AddChangeListenerWithChannel()
+
+	// 无变更时长轮询超时返回，客户端继续监听
+	time.Sleep(300 * time.Millisecond)
+	if got := s.Requests(PathWatchConfigFile); got < 2 {
+		t.Errorf("watch requests = %d, want at least 2", got)
+	}
+
+	s.Publish("ns", "g", "a.txt", "v2")
+	expectEvent(t, events, "a.txt", polarismodel.Modified, "v1", "v2")
+	s.Publish("ns", "g", "b.txt", "new")
+	expectEvent(t, events, "b.txt", polarismodel.Added, "", "new")
+	s.Delete("ns", "g", "a.txt")
+	expectEvent(t, events, "a.txt", polarismodel.Deleted, "v2", "")
+}
+
+func expectEvent(t *testing.T, events <-chan polarismodel.ConfigFileChangeEvent, name string, changeType polarismodel.ChangeType, oldValue, newValue string) {
+	t.Helper()
+	select {
+	case event := <-events:
+		if event.ConfigFileMetadata.GetFileName() != name || event.ChangeType != changeType ||
+			event.OldValue != oldValue || event.NewValue != newValue {
+			t.Errorf("event = %s %v %q -> %q, want %s %v %q -> %q", event.ConfigFileMetadata.GetFileName(), event.ChangeType,
+				event.OldValue, event.NewValue, name, changeType, oldValue, newValue)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change event for %s", name)
+	}
+}
+
+func TestInjectFault(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.Publish("ns", "g", "a.txt", "v1")
+
+	s.InjectFault(PathGetConfigFile, 2, Fault{})
+	s.InjectFault(PathGetConfigFile, 1, Fault{Code: model.Code_NotAllowedAccess, Status: http.StatusForbidden})
+	for i, want := range []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusForbidden, http.StatusOK} {
+		resp, err := getConfigFile(s, "ns", "g", "a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("request %d status = %d, want %d", i, resp.StatusCode, want)
+		}
+	}
+	if got := s.Requests(PathGetConfigFile); got != 4 {
+		t.Errorf("Requests() = %d, want 4", got)
+	}
+
+	// 不限次数的故障一直生效直到清除
+	s.InjectFault(PathGetConfigFile, 0, Fault{Drop: true})
+	for i := 0; i < 3; i++ {
+		resp, err := getConfigFile(s, "ns", "g", "a.txt")
+		if err == nil {
+			// 响应不完整，读取响应体时出错
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		if err == nil {
+			t.Fatalf("request %d with drop fault error = nil", i)
+		}
+	}
+	s.ClearFaults()
+	resp, err := getConfigFile(s, "ns", "g", "a.txt")
+	if err != nil {
+		t.Fatalf("request after ClearFaults error = %v", err)
+	}
+	resp.Body.Close()
+
+	// 返回码透传给 SDK
+	client := newSDK(t, s)
+	s.InjectFault(PathGetConfigFile, 1, Fault{Code: model.Code_NotAllowedAccess})
+	_, err = client.GetConfigFile("ns", "g", "a.txt")
+	var polarisErr *polaris.PolarisError
+	if !errors.As(err, &polarisErr) || polarisErr.Code != model.Code_NotAllowedAccess {
+		t.Errorf("GetConfigFile() error = %v, want NotAllowedAccess", err)
+	}
+}
+
+func TestEncryptedConfigFile(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := newSDK(t, s)
+
+	for _, algo := range []string{crypto.AlgoAES, crypto.AlgoAESV2, crypto.AlgoAESGCM, crypto.AlgoChaCha20Poly1305} {
+		cryptor, err := crypto.GetCryptor(algo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, _ := cryptor.GenerateKey()
+		ciphertext, err := cryptor.Encrypt("secret "+algo, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Publish("ns", "g", algo, ciphertext,
+			sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyUseEncrypted, Value: "true"},
+			sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyEncryptAlgo, Value: algo},
+			sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyDataKey, Value: base64.StdEncoding.EncodeToString(key)},
+		)
+
+		result, err := client.GetConfigFile("ns", "g", algo)
+		if err != nil {
+			t.Fatalf("GetConfigFile() error = %v", err)
+		}
+		// 服务端使用客户端公钥加密返回的数据密钥
+		if result.GetConfigFile().GetDataKey() == base64.StdEncoding.EncodeToString(key) {
+			t.Errorf("%s data key returned in plaintext", algo)
+		}
+		content, err := result.GetConfigFile().GetContent()
+		if err != nil || content != "secret "+algo {
+			t.Errorf("%s GetContent() = %q, %v, want %q", algo, content, err, "secret "+algo)
+		}
+	}
+}
+
+func TestPublishEncryptedLegacyAES(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	config := &configfiles.ConfigFile{Namespace: "ns", Group: "g", FileName: "a.txt", Content: "secret"}
+	if _, err := configfiles.NewClient(client).CreateAndPub(config, configfiles.WithEncryption("", nil)); err != nil {
+		t.Fatalf("CreateAndPub() error = %v", err)
+	}
+	file, ok := s.File("ns", "g", "a.txt")
+	if !ok {
+		t.Fatal("File() published file not found")
+	}
+	if algo := file.GetEncryptAlgo(); algo != crypto.AlgoAES {
+		t.Errorf("encrypt algo = %q, want %q", algo, crypto.AlgoAES)
+	}
+	// AES tag 的密文需要能被北极星控制台及其他 SDK 按旧格式解密
+	key, _ := base64.StdEncoding.DecodeString(file.GetDataKey())
+	want, err := (&crypto.AesCryptor{Legacy: true}).Encrypt("secret", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Content != want {
+		t.Errorf("published content = %q, want legacy ciphertext %q", file.Content, want)
+	}
+}
+
+func TestPublishWithKeyProvider(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	provider, err := crypto.NewFileKeyProvider(t.TempDir() + "/master.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypto.RegisterKeyProvider(provider)
+
+	config := &configfiles.ConfigFile{Namespace: "ns", Group: "g", FileName: "a.txt", Content: "secret"}
+	if _, err := configfiles.NewClient(client).CreateAndPub(config, configfiles.WithKeyProvider(provider)); err != nil {
+		t.Fatalf("CreateAndPub() error = %v", err)
+	}
+	file, ok := s.File("ns", "g", "a.txt")
+	if !ok {
+		t.Fatal("File() published file not found")
+	}
+	// 包装的数据密钥无需兼容旧格式，默认使用带认证的算法
+	if algo := file.GetEncryptAlgo(); algo != crypto.AlgoAESGCM {
+		t.Errorf("encrypt algo = %q, want %q", algo, crypto.AlgoAESGCM)
+	}
+	if content, err := file.GetContent(); err != nil || content != "secret" {
+		t.Errorf("GetContent() = %q, %v, want %q", content, err, "secret")
+	}
+}
@@ -0,0 +1,91 @@
+package polaris
+
+import (
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/time/rate"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RequestKind 请求类别，不同类别使用独立的限流额度
+type RequestKind string
+
+const (
+	// RequestRead 读取配置文件等查询请求
+	RequestRead RequestKind = "read"
+	// RequestWrite 创建、发布、删除配置文件等管理请求
+	RequestWrite RequestKind = "write"
+	// RequestLongPoll 配置文件监听长轮询请求
+	RequestLongPoll RequestKind = "long_poll"
+)
+
+// readPostUris 使用 POST 方法的查询接口
+var readPostUris = []string{
+	"/config/v1/GetConfigFileMetadataList",
+}
+
+// RateLimit 客户端限流配置，Rate 为每秒允许的请求数，Burst 为允许的突发请求数
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// requestKind 根据请求方法和路径判断请求类别，登录请求不限流
+func requestKind(method, path string) (RequestKind, bool) {
+	switch {
+	case strings.HasSuffix(path, loginUri):
+		return "", false
+	case strings.HasSuffix(path, watchFileUri):
+		return RequestLongPoll, true
+	case method == http.MethodGet:
+		return RequestRead, true
+	}
+	for _, uri := range readPostUris {
+		if strings.HasSuffix(path, uri) {
+			return RequestRead, true
+		}
+	}
+	return RequestWrite, true
+}
+
+// rateLimiter 按请求类别限流，等待时响应请求的 context 取消
+type rateLimiter struct {
+	limiters map[RequestKind]*rate.Limiter
+	metrics  Metrics
+}
+
+func newRateLimiter(limits map[RequestKind]RateLimit, metrics Metrics) *rateLimiter {
+	r := &rateLimiter{limiters: map[RequestKind]*rate.Limiter{}, metrics: metrics}
+	for kind, limit := range limits {
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		r.limiters[kind] = rate.NewLimiter(rate.Limit(limit.Rate), burst)
+	}
+	return r
+}
+
+func (r *rateLimiter) middleware(_ *resty.Client, req *resty.Request) error {
+	path := req.URL
+	if u, err := url.Parse(req.URL); err == nil {
+		path = u.Path
+	}
+	kind, ok := requestKind(req.Method, path)
+	if !ok {
+		return nil
+	}
+	limiter, ok := r.limiters[kind]
+	if !ok {
+		return nil
+	}
+
+	start := time.Now()
+	if err := limiter.Wait(req.Context()); err != nil {
+		return err
+	}
+	r.metrics.ObserveRateLimitWait(string(kind), time.Since(start))
+	return nil
+}
@@ -0,0 +1,54 @@
+package sdk
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultFetchConcurrency 批量获取配置文件的默认并发数
+const defaultFetchConcurrency = 8
+
+// ConfigFileRef 配置文件的 命名空间/分组/文件名
+type ConfigFileRef struct {
+	Namespace string
+	Group     string
+	FileName  string
+}
+
+// ConfigFileResult 批量获取时单个配置文件的结果，Err 不为空时 Response 为 nil
+type ConfigFileResult struct {
+	ConfigFileRef
+	Response *ConfigFileResponse
+	Err      error
+}
+
+// GetConfigFiles 并发获取多个配置文件，并发数由 WithFetchConcurrency 设置。
+// 返回的结果与 refs 一一对应，单个文件失败不影响其他文件
+func (s *SDK) GetConfigFiles(ctx context.Context, refs ...ConfigFileRef) []ConfigFileResult {
+	results := make([]ConfigFileResult, len(refs))
+	concurrency := s.fetchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultFetchConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		results[i].ConfigFileRef = ref
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(result *ConfigFileResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result.Response, result.Err = s.GetConfigFileCtx(ctx, result.Namespace, result.Group, result.FileName)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,184 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"github.com/nxsre/polaris-go"
+	"golang.org/x/sync/singleflight"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats 配置文件缓存的统计信息
+type CacheStats struct {
+	// Hits 命中缓存的读取次数
+	Hits uint64
+	// Misses 未命中缓存需要访问北极星的读取次数，合并的并发请求分别计数
+	Misses uint64
+	// Coalesced 与其他并发请求合并、未单独访问北极星的读取次数
+	Coalesced uint64
+	// Entries 当前缓存的配置文件数量
+	Entries int
+}
+
+type cacheKey struct {
+	namespace string
+	group     string
+	fileName  string
+}
+
+func (k cacheKey) String() string {
+	return k.namespace + "\x00" + k.group + "\x00" + k.fileName
+}
+
+type cacheEntry struct {
+	result  *ConfigFileResponse
+	expires time.Time
+}
+
+// cacheResult singleflight 合并请求的结果
+type cacheResult struct {
+	resp   *resty.Response
+	result *ConfigFileResponse
+}
+
+// configCache 配置文件读缓存，并发读取同一文件时只访问一次北极星。
+// 缓存在 ttl 到期或监听到更高版本时失效
+type configCache struct {
+	ttl   time.Duration
+	group singleflight.Group
+
+	lock    sync.RWMutex
+	entries map[cacheKey]*cacheEntry
+	// versions 监听到的最新版本，低于该版本的结果不再写入缓存
+	versions map[cacheKey]uint64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	coalesced atomic.Uint64
+}
+
+func newConfigCache(ttl time.Duration) *configCache {
+	return &configCache{
+		ttl:      ttl,
+		entries:  map[cacheKey]*cacheEntry{},
+		versions: map[cacheKey]uint64{},
+	}
+}
+
+// get 读取缓存，未命中时调用 load 获取。并发调用共享同一次 load，
+// load 使用先到请求的 ctx，该请求取消时其他请求会重新发起 load
+func (c *configCache) get(ctx context.Context, key cacheKey,
+	load func(ctx context.Context) (*resty.Response, *ConfigFileResponse, error)) (*resty.Response, *ConfigFileResponse, bool, error) {
+	if result, ok := c.lookup(key); ok {
+		c.hits.Add(1)
+		return nil, result, true, nil
+	}
+	c.misses.Add(1)
+
+	for {
+		// 只有发起请求的调用会执行 load，其余调用共享其结果
+		leader := false
+		ch := c.group.DoChan(key.String(), func() (any, error) {
+			leader = true
+			resp, result, err := load(ctx)
+			if err == nil {
+				c.store(key, result)
+			} else if polaris.IsNotFound(err) {
+				c.remove(key)
+			}
+			return &cacheResult{resp: resp, result: result}, err
+		})
+		select {
+		case <-ctx.Done():
+			return nil, nil, false, ctx.Err()
+		case res := <-ch:
+			if res.Shared && !leader {
+				c.coalesced.Add(1)
+			}
+			if res.Err != nil && errors.Is(res.Err, context.Canceled) && ctx.Err() == nil {
+				continue
+			}
+			shared := res.Val.(*cacheResult)
+			return shared.resp, copyResponse(shared.result), false, res.Err
+		}
+	}
+}
+
+func (c *configCache) lookup(key cacheKey) (*ConfigFileResponse, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	entry, ok := c.entries[key]
+	if !ok || (c.ttl > 0 && time.Now().After(entry.expires)) {
+		return nil, false
+	}
+	return copyResponse(entry.result), true
+}
+
+// store 写入缓存，本地快照及低于已监听到版本的结果不缓存
+func (c *configCache) store(key cacheKey, result *ConfigFileResponse) {
+	if result == nil || result.Stale || result.GetConfigFile() == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if result.GetConfigFile().GetVersion() < c.versions[key] {
+		return
+	}
+	c.entries[key] = &cacheEntry{result: result, expires: time.Now().Add(c.ttl)}
+}
+
+func (c *configCache) remove(key cacheKey) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, key)
+}
+
+// invalidate 监听到 version 版本后使低版本的缓存失效，并使之后的读取不再共享进行中的请求
+func (c *configCache) invalidate(key cacheKey, version uint64) {
+	c.lock.Lock()
+	if version > c.versions[key] {
+		c.versions[key] = version
+	}
+	if entry, ok := c.entries[key]; ok && entry.result.GetConfigFile().GetVersion() < version {
+		delete(c.entries, key)
+	}
+	c.lock.Unlock()
+	c.group.Forget(key.String())
+}
+
+func (c *configCache) stats() CacheStats {
+	c.lock.RLock()
+	entries := len(c.entries)
+	c.lock.RUnlock()
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Coalesced: c.coalesced.Load(),
+		Entries:   entries,
+	}
+}
+
+// copyResponse 复制响应，避免调用方修改缓存的内容
+func copyResponse(result *ConfigFileResponse) *ConfigFileResponse {
+	if result == nil {
+		return nil
+	}
+	copied := *result
+	if result.ConfigFile != nil {
+		file := *result.ConfigFile
+		file.Tags = append([]ConfigFileTag(nil), file.Tags...)
+		copied.ConfigFile = &file
+	}
+	return &copied
+}
+
+// CacheStats 获取配置文件缓存的统计信息，未开启 WithCache 时返回零值
+func (s *SDK) CacheStats() CacheStats {
+	if s.cache == nil {
+		return CacheStats{}
+	}
+	return s.cache.stats()
+}
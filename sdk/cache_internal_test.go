@@ -0,0 +1,102 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func versionResponse(version string) *ConfigFileResponse {
+	return &ConfigFileResponse{ConfigFile: &ConfigFile{Version: version}}
+}
+
+func TestConfigCacheLeaderCanceled(t *testing.T) {
+	c := newConfigCache(0)
+	key := cacheKey{"ns", "g", "a.txt"}
+	var loads atomic.Int32
+	load := func(ctx context.Context) (*resty.Response, *ConfigFileResponse, error) {
+		loads.Add(1)
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+			return nil, versionResponse("1"), nil
+		}
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, _, err := c.get(leaderCtx, key, load)
+		leaderErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	followerDone := make(chan struct{})
+	var result *ConfigFileResponse
+	var err error
+	go func() {
+		defer close(followerDone)
+		_, result, _, err = c.get(context.Background(), key, load)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader get() error = %v, want context.Canceled", err)
+	}
+	// 先到请求取消后，共享它的请求使用自己的 ctx 重新获取
+	<-followerDone
+	if err != nil || result.GetConfigFile().Version != "1" {
+		t.Errorf("follower get() = %+v, %v, want version 1", result, err)
+	}
+	if got := loads.Load(); got != 2 {
+		t.Errorf("loads = %d, want 2", got)
+	}
+}
+
+func TestConfigCacheInvalidate(t *testing.T) {
+	c := newConfigCache(0)
+	key := cacheKey{"ns", "g", "a.txt"}
+	ctx := context.Background()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	staleDone := make(chan struct{})
+	go func() {
+		defer close(staleDone)
+		c.get(ctx, key, func(context.Context) (*resty.Response, *ConfigFileResponse, error) {
+			close(started)
+			<-release
+			return nil, versionResponse("1"), nil
+		})
+	}()
+	<-started
+
+	// 监听到新版本后的读取不共享进行中的旧请求
+	c.invalidate(key, 2)
+	_, result, _, err := c.get(ctx, key, func(context.Context) (*resty.Response, *ConfigFileResponse, error) {
+		return nil, versionResponse("2"), nil
+	})
+	if err != nil || result.GetConfigFile().Version != "2" {
+		t.Fatalf("get() after invalidate = %+v, %v, want version 2", result, err)
+	}
+
+	// 旧请求的结果低于监听到的版本，不覆盖缓存
+	close(release)
+	<-staleDone
+	_, result, hit, err := c.get(ctx, key, func(context.Context) (*resty.Response, *ConfigFileResponse, error) {
+		t.Error("load called, want cache hit")
+		return nil, nil, errors.New("unexpected load")
+	})
+	if err != nil || !hit || result.GetConfigFile().Version != "2" {
+		t.Errorf("get() = %+v, hit %v, %v, want cached version 2", result, hit, err)
+	}
+
+	// 缓存的版本低于监听到的版本时失效
+	c.invalidate(key, 3)
+	if _, ok := c.lookup(key); ok {
+		t.Error("lookup() after invalidate found version 2, want miss")
+	}
+}
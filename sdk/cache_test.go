@@ -0,0 +1,75 @@
+package sdk_test
+
+import (
+	"github.com/nxsre/polaris-go/polaristest"
+	"github.com/nxsre/polaris-go/sdk"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheCoalesce(t *testing.T) {
+	s := polaristest.NewServer()
+	defer s.Close()
+	client := newTestSDK(t, s, sdk.WithCache(time.Minute))
+	s.Publish("ns", "g", "a.txt", "v1")
+	s.SetLatency(200 * time.Millisecond)
+
+	const readers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := client.GetConfigFile("ns", "g", "a.txt")
+			if err != nil || result.GetConfigFile().GetSourceContent() != "v1" {
+				t.Errorf("GetConfigFile() = %+v, %v, want content v1", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := s.Requests(polaristest.PathGetConfigFile); got != 1 {
+		t.Errorf("server requests = %d, want 1", got)
+	}
+	want := sdk.CacheStats{Misses: readers, Coalesced: readers - 1, Entries: 1}
+	if got := client.CacheStats(); got != want {
+		t.Errorf("CacheStats() = %+v, want %+v", got, want)
+	}
+
+	// 命中缓存不访问北极星，返回的是副本
+	result, err := client.GetConfigFile("ns", "g", "a.txt")
+	if err != nil {
+		t.Fatalf("GetConfigFile() error = %v", err)
+	}
+	result.GetConfigFile().Content = "modified"
+	result, _ = client.GetConfigFile("ns", "g", "a.txt")
+	if content := result.GetConfigFile().GetSourceContent(); content != "v1" {
+		t.Errorf("cached content = %q after caller modified the result, want v1", content)
+	}
+	if got := s.Requests(polaristest.PathGetConfigFile); got != 1 {
+		t.Errorf("server requests after cache hits = %d, want 1", got)
+	}
+	if got := client.CacheStats().Hits; got != 2 {
+		t.Errorf("CacheStats().Hits = %d, want 2", got)
+	}
+}
+
+func TestCacheInvalidateOnWatch(t *testing.T) {
+	s := polaristest.NewServer()
+	defer s.Close()
+	client := newTestSDK(t, s, sdk.WithCache(0))
+	s.Publish("ns", "g", "a.txt", "v1")
+
+	watcher, err := client.WatchConfigFiles("ns", "g", "a.txt")
+	if err != nil {
+		t.Fatalf("WatchConfigFiles() error = %v", err)
+	}
+	events := watcher.AddChangeListenerWithChannel()
+	s.Publish("ns", "g", "a.txt", "v2")
+	expectChange(t, events, "v1", "v2")
+
+	result, err := client.GetConfigFile("ns", "g", "a.txt")
+	if err != nil || result.GetConfigFile().GetSourceContent() != "v2" {
+		t.Errorf("GetConfigFile() after change = %+v, %v, want content v2", result, err)
+	}
+}
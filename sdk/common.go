@@ -5,10 +5,14 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/nxsre/polaris-go"
 	"net/url"
+	"path/filepath"
 )
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
+// PolarisUrl 使用默认访问地址拼接接口地址
+//
+// Deprecated: 使用 Polaris.URL，以支持 polaris.WithBaseURL 指定的访问地址
 func PolarisUrl(uri string) string {
 	fullUrl, _ := url.JoinPath(baseUrl, uri)
 	return fullUrl
@@ -16,12 +20,36 @@ func PolarisUrl(uri string) string {
 
 type SDK struct {
 	polarisClient *polaris.Polaris
-	ctx           context.Context
+	// ctx 控制后台监听任务的生命周期，单次请求使用各方法 Ctx 版本传入的 context
+	ctx context.Context
+	// snapshots 配置文件本地快照，未开启时为 nil
+	snapshots *snapshotStore
+	// cache 配置文件读缓存，未开启时为 nil
+	cache *configCache
+	// fetchConcurrency 批量获取配置文件的并发数
+	fetchConcurrency int
+	// rsaKey 保护数据密钥的 RSA 密钥对
+	rsaKey *rsaKey
+	// trees 配置文件按路径查找使用的解析结果缓存
+	trees *treeCache
+	// dataKeys 密钥提供者解包后的数据密钥缓存
+	dataKeys *dataKeyCache
 }
 
-func NewSDK(ctx context.Context, client *polaris.Polaris) *SDK {
-	return &SDK{
+func NewSDK(ctx context.Context, client *polaris.Polaris, opts ...Option) *SDK {
+	s := &SDK{
 		polarisClient: client,
 		ctx:           ctx,
+		rsaKey:        &rsaKey{},
+		trees:         newLRUCache[*parsedTree](defaultTreeCacheSize),
+		dataKeys:      newLRUCache[*dataKeyEntry](defaultDataKeyCacheSize),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// 快照中保存的是公钥加密的数据密钥，未指定密钥对时将生成的密钥对保存在快照目录，进程重启后继续使用
+	if s.snapshots != nil && s.rsaKey.key == nil {
+		s.rsaKey.path = filepath.Join(s.snapshots.dir, rsaKeyFile)
+	}
+	return s
 }
@@ -0,0 +1,24 @@
+package sdk_test
+
+import (
+	"context"
+	"github.com/nxsre/polaris-go"
+	"github.com/nxsre/polaris-go/polaristest"
+	"github.com/nxsre/polaris-go/sdk"
+	"testing"
+)
+
+// newTestSDK 创建访问模拟服务的 SDK，不重试失败的请求，测试结束时关闭
+func newTestSDK(t *testing.T, s *polaristest.Server, opts ...sdk.Option) *sdk.SDK {
+	t.Helper()
+	client, err := s.Client(polaris.WithRetry(0, 0, 0))
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		client.Close()
+	})
+	return sdk.NewSDK(ctx, client, opts...)
+}
@@ -29,58 +29,98 @@ func (s *SDK) NewConfdClient() *Confd {
 	}
 }
 
+// GetValues 获取 keys 对应的配置文件内容
 func (c *Confd) GetValues(keys []string) (map[string]string, error) {
-	result := map[string]string{}
+	return c.GetValuesCtx(context.Background(), keys)
+}
+
+// GetValuesCtx 获取 keys 对应的配置文件内容，ctx 控制获取文件列表及配置文件请求的超时及取消
+func (c *Confd) GetValuesCtx(ctx context.Context, keys []string) (map[string]string, error) {
+	// 先收集所有需要获取的配置文件，再并发获取
+	type valueKey struct {
+		group    string
+		fileName string
+		wildcard bool
+		refs     []ConfigFileRef
+	}
+	valueKeys := []valueKey{}
+	refs := []ConfigFileRef{}
+	seen := map[ConfigFileRef]struct{}{}
 	for _, k := range keys {
 		namespace, group, fileName := parseKey(c.prefix, k)
+		key := valueKey{group: group, fileName: fileName}
 		if !match(wildCardToRegexp("*"), fileName) {
-			configFileResult, err := c.sdk.GetConfigFile(namespace, group, fileName)
+			key.refs = []ConfigFileRef{{Namespace: namespace, Group: group, FileName: fileName}}
+		} else {
+			key.wildcard = true
+			pattern := regexp.MustCompilePOSIX(wildCardToRegexp(fileName))
+			configFilesResult, err := c.sdk.GetConfigFileMetadataListCtx(ctx, namespace, group)
 			if err != nil {
-				log.Errorln(err)
+				return nil, err
+			}
+			for _, cfgFile := range configFilesResult.ConfigFileInfos {
+				if pattern.MatchString(cfgFile.FileName) {
+					key.refs = append(key.refs, ConfigFileRef{Namespace: namespace, Group: group, FileName: cfgFile.FileName})
+				}
+			}
+		}
+		valueKeys = append(valueKeys, key)
+		for _, ref := range key.refs {
+			if _, ok := seen[ref]; !ok {
+				seen[ref] = struct{}{}
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	files := map[ConfigFileRef]ConfigFileResult{}
+	for _, file := range c.sdk.GetConfigFiles(ctx, refs...) {
+		files[file.ConfigFileRef] = file
+	}
+
+	result := map[string]string{}
+	for _, key := range valueKeys {
+		if !key.wildcard {
+			file := files[key.refs[0]]
+			if file.Err != nil {
+				log.Errorln(file.Err)
 				continue
 			}
-			content, err := configFileResult.GetConfigFile().GetContent()
+			content, err := file.Response.GetConfigFile().GetContent()
 			if err != nil {
 				log.Errorln(err)
 				continue
 			}
-			result[filepath.Join(string(os.PathSeparator), group, fileName)] = content
-		} else {
-			pattern := regexp.MustCompilePOSIX(wildCardToRegexp(fileName))
-			configFilesResult, err := c.sdk.GetConfigFileMetadataList(namespace, group)
-			if err != nil {
-				return nil, err
-			}
+			result[filepath.Join(string(os.PathSeparator), key.group, key.fileName)] = content
+			continue
+		}
 
-			tmpResult := []map[string]interface{}{}
-			for _, cfgFile := range configFilesResult.ConfigFileInfos {
-				if pattern.MatchString(cfgFile.FileName) {
-					var contents = map[string]interface{}{}
-					configFile, err := c.sdk.GetConfigFile(namespace, group, cfgFile.FileName)
-					if err != nil {
-						log.Errorln(err)
-						continue
-					}
+		tmpResult := []map[string]interface{}{}
+		for _, ref := range key.refs {
+			var contents = map[string]interface{}{}
+			file := files[ref]
+			if file.Err != nil {
+				log.Errorln(file.Err)
+				continue
+			}
 
-					fileConent, err := configFile.GetConfigFile().GetContent()
-					if err != nil {
-						log.Errorln(err)
-						continue
-					}
-					if fileConent != "" {
-						if err := jsoniter.UnmarshalFromString(fileConent, &contents); err != nil {
-							log.Errorln(err)
-							continue
-						} else {
-							tmpResult = append(tmpResult, contents)
-						}
-					}
+			fileConent, err := file.Response.GetConfigFile().GetContent()
+			if err != nil {
+				log.Errorln(err)
+				continue
+			}
+			if fileConent != "" {
+				if err := jsoniter.UnmarshalFromString(fileConent, &contents); err != nil {
+					log.Errorln(err)
+					continue
+				} else {
+					tmpResult = append(tmpResult, contents)
 				}
 			}
-
-			jb, err := jsoniter.MarshalIndent(&tmpResult, "", " ")
-			result[filepath.Join(string(os.PathSeparator), group, fileName)] = string(jb)
 		}
+
+		jb, _ := jsoniter.MarshalIndent(&tmpResult, "", " ")
+		result[filepath.Join(string(os.PathSeparator), key.group, key.fileName)] = string(jb)
 	}
 	return result, nil
 }
@@ -153,7 +193,7 @@ func (w *Watch) WaitNext(ctx context.Context, c *SDK, lastRevision int64, notify
 				for {
 					select {
 					case <-ticker.C:
-						configFilesResult, err := c.GetConfigFileMetadataList(w.namespace, w.group)
+						configFilesResult, err := c.GetConfigFileMetadataListCtx(ctx, w.namespace, w.group)
 						if err != nil {
 							log.Errorln(err)
 							return
@@ -1,13 +1,21 @@
 package sdk
 
 import (
+	"context"
+	"crypto/rsa"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/nxsre/polaris-go"
 	"github.com/nxsre/polaris-go/crypto"
 	"github.com/nxsre/polaris-go/log"
 	"github.com/polarismesh/specification/source/go/api/v1/model"
-
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"os"
 	"strconv"
+	"time"
 )
 
 const (
@@ -17,8 +25,17 @@ const (
 	ConfigFileTagKeyDataKey = "internal-datakey"
 	// ConfigFileTagKeyEncryptAlgo 加密算法 tag key
 	ConfigFileTagKeyEncryptAlgo = "internal-encryptalgo"
+	// ConfigFileTagKeyWrappedDataKey 密钥提供者包装后的加密密钥 tag key，存在时不使用 internal-datakey
+	ConfigFileTagKeyWrappedDataKey = "internal-wrapped-datakey"
+	// ConfigFileTagKeyKeyProvider 包装加密密钥的密钥提供者名称 tag key
+	ConfigFileTagKeyKeyProvider = "internal-keyprovider"
+
+	baseUrl = polaris.DefaultBaseURL
 
-	baseUrl = "http://polaris.com"
+	// unwrapDataKeyTimeout 调用密钥提供者解包数据密钥的超时时间
+	unwrapDataKeyTimeout = 10 * time.Second
+	// defaultDataKeyCacheSize 每个 SDK 缓存解包后数据密钥的配置文件数量上限
+	defaultDataKeyCacheSize = 256
 )
 
 type ConfigFile struct {
@@ -28,6 +45,8 @@ type ConfigFile struct {
 	FileName  string          `json:"fileName"`
 	Content   string          `json:"content,omitempty"`
 	Tags      []ConfigFileTag `json:"tags,omitempty"`
+	// Format 配置文件格式，如 json、yaml、properties、toml、xml、ini
+	Format string `json:"format,omitempty"`
 
 	// 查询返回
 	Version     string `json:"version,omitempty"`
@@ -36,6 +55,13 @@ type ConfigFile struct {
 	PublicKey   string `json:"publicKey,omitempty"`
 	Name        string `json:"name,omitempty"`
 	ReleaseTime any    `json:"release_time,omitempty"`
+
+	// privateKey 解密数据密钥的私钥，由获取配置文件的 SDK 设置
+	privateKey *rsa.PrivateKey
+	// trees 获取配置文件的 SDK 的解析结果缓存，为 nil 时每次重新解析
+	trees *treeCache
+	// dataKeys 获取配置文件的 SDK 的数据密钥解包结果缓存，为 nil 时每次调用密钥提供者解包
+	dataKeys *dataKeyCache
 }
 
 type ConfigFileTag struct {
@@ -98,22 +124,125 @@ func (c *ConfigFile) GetDataKey() string {
 	return ""
 }
 
-// GetContent 获取配置文件内容
+// DecryptError 解密配置文件内容失败，Err 可能为 crypto.ErrUnsupportedAlgo、crypto.ErrInvalidKey、
+// crypto.ErrInvalidCiphertext、crypto.ErrUnknownKeyProvider 或数据密钥解码、解包、解密的错误
+type DecryptError struct {
+	Namespace string
+	Group     string
+	FileName  string
+	Algo      string
+	Err       error
+}
+
+func (e *DecryptError) Error() string {
+	return fmt.Sprintf("polaris: decrypt config file %s/%s/%s with algo %q: %v", e.Namespace, e.Group, e.FileName, e.Algo, e.Err)
+}
+
+func (e *DecryptError) Unwrap() error {
+	return e.Err
+}
+
+// GetContent 获取配置文件内容，加密文件按 internal-encryptalgo tag 从 crypto 注册的算法中选择解密算法，
+// 解密失败时返回 *DecryptError
 func (c *ConfigFile) GetContent() (string, error) {
-	if c.GetEncrypted() {
-		switch c.GetEncryptAlgo() {
-		case "AES":
-			aes := crypto.AesCryptor{}
-			key, err := base64.StdEncoding.DecodeString(c.GetDataKey())
-			if err != nil {
-				return "", err
-			}
-			return aes.Decrypt(c.Content, key)
-		default:
-			return "", errors.New("not support algo")
+	if !c.GetEncrypted() {
+		return c.Content, nil
+	}
+	content, err := c.decrypt()
+	if err != nil {
+		return "", &DecryptError{Namespace: c.Namespace, Group: c.Group, FileName: c.FileName, Algo: c.GetEncryptAlgo(), Err: err}
+	}
+	return content, nil
+}
+
+func (c *ConfigFile) decrypt() (string, error) {
+	cryptor, err := crypto.GetCryptor(c.GetEncryptAlgo())
+	if err != nil {
+		return "", err
+	}
+	key, err := c.dataKey()
+	if err != nil {
+		return "", err
+	}
+	return cryptor.Decrypt(c.Content, key)
+}
+
+// dataKey 获取解密后的数据密钥，获取配置文件时发送了公钥的，数据密钥为公钥加密的密文
+func (c *ConfigFile) dataKey() ([]byte, error) {
+	if wrapped := c.GetWrappedDataKey(); wrapped != "" {
+		return c.unwrapDataKey(wrapped)
+	}
+	key, err := base64.StdEncoding.DecodeString(c.GetDataKey())
+	if err != nil {
+		return nil, err
+	}
+	if crypto.IsRSACiphertext(key, c.privateKey) {
+		return crypto.RSADecrypt(key, c.privateKey)
+	}
+	return key, nil
+}
+
+// dataKeyCache 每个文件最新版本解包后的数据密钥
+type dataKeyCache = lruCache[*dataKeyEntry]
+
+type dataKeyEntry struct {
+	version  string
+	provider string
+	wrapped  string
+	key      []byte
+}
+
+// unwrapDataKey 使用 internal-keyprovider tag 指定的密钥提供者解包数据密钥，提供者需要先通过
+// crypto.RegisterKeyProvider 注册。同一版本的配置文件只解包一次，密钥提供者调用超过
+// unwrapDataKeyTimeout 时返回错误
+func (c *ConfigFile) unwrapDataKey(wrapped string) ([]byte, error) {
+	key := cacheKey{c.Namespace, c.Group, c.FileName}
+	cacheable := c.dataKeys != nil && c.Version != ""
+	if cacheable {
+		if entry, ok := c.dataKeys.get(key); ok && entry.version == c.Version &&
+			entry.provider == c.GetKeyProvider() && entry.wrapped == wrapped {
+			return entry.key, nil
+		}
+	}
+
+	provider, err := crypto.GetKeyProvider(c.GetKeyProvider())
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), unwrapDataKeyTimeout)
+	defer cancel()
+	dataKey, err := provider.UnwrapKey(ctx, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		c.dataKeys.set(key, &dataKeyEntry{version: c.Version, provider: c.GetKeyProvider(), wrapped: wrapped, key: dataKey})
+	}
+	return dataKey, nil
+}
+
+// GetWrappedDataKey 获取密钥提供者包装后的数据加密密钥
+func (c *ConfigFile) GetWrappedDataKey() string {
+	return c.getTag(ConfigFileTagKeyWrappedDataKey)
+}
+
+// GetKeyProvider 获取包装数据加密密钥的密钥提供者名称
+func (c *ConfigFile) GetKeyProvider() string {
+	return c.getTag(ConfigFileTagKeyKeyProvider)
+}
+
+func (c *ConfigFile) getTag(key string) string {
+	for _, tag := range c.Tags {
+		if tag.Key == key {
+			return tag.Value
 		}
 	}
-	return c.Content, nil
+	return ""
 }
 
 // GetEncryptAlgo 获取配置文件数据加密算法
@@ -126,24 +255,91 @@ func (c *ConfigFile) GetEncryptAlgo() string {
 	return ""
 }
 
+// GetConfigFile 获取配置文件，返回码非成功时返回 *polaris.PolarisError
 func (s *SDK) GetConfigFile(ns, group, filename string) (*ConfigFileResponse, error) {
-	resp, err := s.polarisClient.Resty().R().SetQueryParams(map[string]string{
+	return s.GetConfigFileCtx(context.Background(), ns, group, filename)
+}
+
+// GetConfigFileCtx 获取配置文件，ctx 控制本次请求的超时及取消。
+// 开启 WithSnapshotDir 时，北极星不可用会返回本地快照，响应的 Stale 为 true
+func (s *SDK) GetConfigFileCtx(ctx context.Context, ns, group, filename string) (result *ConfigFileResponse, err error) {
+	ctx, span := s.polarisClient.StartSpan(ctx, "polaris.GetConfigFile", polaris.ConfigFileAttributes(ns, group, filename)...)
+	var resp *resty.Response
+	defer func() { polaris.EndSpan(span, resp, result, err) }()
+
+	if s.cache == nil {
+		resp, result, err = s.loadConfigFile(ctx, ns, group, filename)
+		return result, err
+	}
+	var hit bool
+	resp, result, hit, err = s.cache.get(ctx, cacheKey{ns, group, filename},
+		func(ctx context.Context) (*resty.Response, *ConfigFileResponse, error) {
+			return s.loadConfigFile(ctx, ns, group, filename)
+		})
+	span.SetAttributes(attribute.Bool("polaris.cache_hit", hit))
+	return result, err
+}
+
+// loadConfigFile 从北极星获取配置文件，开启 WithSnapshotDir 时维护本地快照并在北极星不可用时读取快照
+func (s *SDK) loadConfigFile(ctx context.Context, ns, group, filename string) (*resty.Response, *ConfigFileResponse, error) {
+	resp, result, err := s.fetchConfigFile(ctx, ns, group, filename)
+	if s.snapshots == nil {
+		return resp, result, err
+	}
+	switch {
+	case err == nil:
+		if err := s.snapshots.save(result.GetConfigFile()); err != nil {
+			log.Errorf("save snapshot of config file %s/%s/%s: %v", ns, group, filename, err)
+		}
+	case polaris.IsNotFound(err):
+		if err := s.snapshots.remove(ns, group, filename); err != nil {
+			log.Errorf("remove snapshot of config file %s/%s/%s: %v", ns, group, filename, err)
+		}
+	case isUnavailable(ctx, err):
+		snapshot, snapshotErr := s.snapshots.load(ns, group, filename)
+		if snapshotErr != nil {
+			if !errors.Is(snapshotErr, os.ErrNotExist) {
+				log.Errorf("load snapshot of config file %s/%s/%s: %v", ns, group, filename, snapshotErr)
+			}
+			return resp, nil, err
+		}
+		log.Warnf("polaris unavailable, use snapshot of config file %s/%s/%s saved at %s: %v",
+			ns, group, filename, snapshot.SnapshotTime.Format(time.RFC3339), err)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("polaris.stale", true))
+		snapshot.ConfigFile.privateKey, _, _ = s.rsaKey.get()
+		snapshot.ConfigFile.trees = s.trees
+		snapshot.ConfigFile.dataKeys = s.dataKeys
+		return resp, snapshot, nil
+	}
+	return resp, result, err
+}
+
+func (s *SDK) fetchConfigFile(ctx context.Context, ns, group, filename string) (*resty.Response, *ConfigFileResponse, error) {
+	privateKey, publicKey, err := s.rsaKey.get()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.polarisClient.Resty().R().SetContext(ctx).SetQueryParams(map[string]string{
 		"namespace": ns,
 		"group":     group,
 		"fileName":  filename,
-	}).Get(PolarisUrl("/config/v1/GetConfigFile"))
+		"publicKey": publicKey,
+	}).Get(s.polarisClient.URL("/config/v1/GetConfigFile"))
 	if err != nil {
 		log.Errorln(resp, err)
-		return nil, err
+		return resp, nil, err
 	}
 	result := &ConfigFileResponse{}
-	err = json.Unmarshal(resp.Body(), result)
-	if err != nil {
-		log.Errorln(resp, err)
-		return nil, err
+	if err := polaris.ParseResponse(resp, result); err != nil {
+		return resp, nil, err
+	}
+	if result.ConfigFile != nil {
+		result.ConfigFile.privateKey = privateKey
+		result.ConfigFile.trees = s.trees
+		result.ConfigFile.dataKeys = s.dataKeys
 	}
 
-	return result, nil
+	return resp, result, nil
 }
 
 // ConfigFileResponse 配置文件响应体
@@ -151,6 +347,10 @@ type ConfigFileResponse struct {
 	Code       model.Code
 	Info       string
 	ConfigFile *ConfigFile `json:"configFile"`
+
+	// Stale 为 true 时北极星不可用，ConfigFile 为 SnapshotTime 时保存的本地快照，可能不是最新版本
+	Stale        bool      `json:"-"`
+	SnapshotTime time.Time `json:"-"`
 }
 
 // GetCode 获取配置文件响应体code
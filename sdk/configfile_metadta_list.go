@@ -1,5 +1,12 @@
 package sdk
 
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"github.com/nxsre/polaris-go"
+	"github.com/polarismesh/specification/source/go/api/v1/model"
+)
+
 type ConfigFileMetadataListRequest struct {
 	ConfigFileGroup ConfigFileGroup `json:"config_file_group"`
 }
@@ -17,20 +24,39 @@ type ConfigFileMetadataListResult struct {
 	ConfigFileInfos []ConfigFile `json:"config_file_infos"`
 }
 
-// GetConfigFileMetadata 获取分组下的文件列表
+// GetCode 获取响应体code
+func (c *ConfigFileMetadataListResult) GetCode() model.Code {
+	return model.Code(c.Code)
+}
+
+// GetMessage 获取响应体信息
+func (c *ConfigFileMetadataListResult) GetMessage() string {
+	return c.Info
+}
+
+// GetConfigFileMetadata 获取分组下的文件列表，返回码非成功时返回 *polaris.PolarisError
 func (s *SDK) GetConfigFileMetadataList(ns, group string) (*ConfigFileMetadataListResult, error) {
-	resp, err := s.polarisClient.Resty().R().SetBody(&ConfigFileMetadataListRequest{
+	return s.GetConfigFileMetadataListCtx(context.Background(), ns, group)
+}
+
+// GetConfigFileMetadataListCtx 获取分组下的文件列表，ctx 控制本次请求的超时及取消
+func (s *SDK) GetConfigFileMetadataListCtx(ctx context.Context, ns, group string) (result *ConfigFileMetadataListResult, err error) {
+	ctx, span := s.polarisClient.StartSpan(ctx, "polaris.GetConfigFileMetadataList",
+		polaris.AttrNamespace.String(ns), polaris.AttrGroup.String(group))
+	var resp *resty.Response
+	defer func() { polaris.EndSpan(span, resp, result, err) }()
+
+	resp, err = s.polarisClient.Resty().R().SetContext(ctx).SetBody(&ConfigFileMetadataListRequest{
 		ConfigFileGroup: ConfigFileGroup{
 			Namespace: ns,
 			Name:      group,
-		}}).Post(PolarisUrl("/config/v1/GetConfigFileMetadataList"))
+		}}).Post(s.polarisClient.URL("/config/v1/GetConfigFileMetadataList"))
 	if err != nil {
 		return nil, err
 	}
 
-	result := &ConfigFileMetadataListResult{}
-	err = json.Unmarshal(resp.Body(), result)
-	if err != nil {
+	result = &ConfigFileMetadataListResult{}
+	if err := polaris.ParseResponse(resp, result); err != nil {
 		return nil, err
 	}
 
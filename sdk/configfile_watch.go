@@ -1,15 +1,23 @@
 package sdk
 
 import (
+	"context"
 	"errors"
+	"github.com/go-resty/resty/v2"
+	"github.com/nxsre/polaris-go"
 	"github.com/nxsre/polaris-go/log"
 	model "github.com/polarismesh/polaris-go/pkg/model"
 	specmodel "github.com/polarismesh/specification/source/go/api/v1/model"
+	"go.opentelemetry.io/otel/attribute"
 	"sync"
+	"time"
 )
 
 const (
 	NotExistedFileContent = string("@@not_existed@@")
+
+	// watchRetryInterval 监听请求失败后的重试间隔
+	watchRetryInterval = 3 * time.Second
 )
 
 type WatchFilesRequest struct {
@@ -20,12 +28,14 @@ type WatchFile struct {
 	Group     string `json:"group"`
 	FileName  string `json:"file_name"`
 	Version   uint64 `json:"version"`
+	// PublicKey 客户端公钥，北极星使用它加密返回的数据密钥
+	PublicKey string `json:"public_key,omitempty"`
 
 	// 旧版本内容
 	content string `json:"-"`
 }
 
-func (w WatchFile) SetContent(str string) {
+func (w *WatchFile) SetContent(str string) {
 	w.content = str
 }
 
@@ -36,6 +46,9 @@ type ConfigFilesWatcher struct {
 	lock                sync.RWMutex
 	changeListeners     []func(event model.ConfigFileChangeEvent)
 	changeListenerChans []chan model.ConfigFileChangeEvent
+	errorListeners      []func(err error)
+	// setupErrs 首次获取配置文件失败或解密失败的错误，增加错误监听器时回调
+	setupErrs []error
 }
 
 // AddChangeListenerWithChannel 增加配置文件变更监听器
@@ -56,7 +69,35 @@ func (w *ConfigFilesWatcher) AddChangeListener(cb model.OnConfigFileChange) {
 	log.Infoln(w.changeListeners)
 }
 
+// AddErrorListener 增加错误监听器，增加时先回调首次获取配置文件失败或解密失败的错误。
+// 变更后的配置文件解密失败时回调 *DecryptError，此时不触发变更事件，监听器保留上一次的内容；
+// 获取变更后的配置文件遇到无法重试的错误时回调该错误，等待后重试；监听请求遇到无法重试的错误停止监听时回调该错误
+func (w *ConfigFilesWatcher) AddErrorListener(cb func(err error)) {
+	w.lock.Lock()
+	w.errorListeners = append(w.errorListeners, cb)
+	setupErrs := w.setupErrs
+	w.lock.Unlock()
+
+	for _, err := range setupErrs {
+		cb(err)
+	}
+}
+
+func (w *ConfigFilesWatcher) fireError(err error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	for _, errorListener := range w.errorListeners {
+		errorListener(err)
+	}
+}
+
 func (w *ConfigFilesWatcher) fireChangeEvent(event model.ConfigFileChangeEvent) {
+	start := time.Now()
+	defer func() {
+		w.sdk.polarisClient.Metrics().ObserveEventFanout(time.Since(start))
+	}()
+
 	log.Infof("==== event: %+v %+v", w.changeListenerChans, w.changeListeners)
 	for _, listenerChan := range w.changeListenerChans {
 		log.Infof("++++ listenerChan event: %+v", event)
@@ -73,63 +114,126 @@ var (
 	num int64 = 0
 )
 
+// longPoll 发起一次监听长轮询，有文件变更或超时无变更时返回
+func (w *ConfigFilesWatcher) longPoll() (result *ConfigFileResponse, err error) {
+	ctx, span := w.sdk.polarisClient.StartSpan(w.sdk.ctx, "polaris.WatchConfigFile",
+		attribute.Int("polaris.watch_files", len(w.watchFiles)))
+	var resp *resty.Response
+	defer func() {
+		if err == nil && result.GetConfigFile() != nil {
+			file := result.GetConfigFile()
+			span.SetAttributes(polaris.ConfigFileAttributes(file.GetNamespace(), file.GetFileGroup(), file.GetFileName())...)
+		}
+		polaris.EndSpan(span, resp, result, err)
+	}()
+
+	_, publicKey, err := w.sdk.rsaKey.get()
+	if err != nil {
+		return nil, err
+	}
+	files := []WatchFile{}
+	for _, file := range w.watchFiles {
+		file.PublicKey = publicKey
+		files = append(files, file)
+	}
+	resp, err = w.sdk.polarisClient.Resty().R().SetContext(ctx).SetBody(&WatchFilesRequest{files}).
+		Post(w.sdk.polarisClient.URL("/config/v1/WatchConfigFile"))
+	if err != nil {
+		return nil, err
+	}
+
+	result = &ConfigFileResponse{}
+	if err := polaris.ParseResponse(resp, result, specmodel.Code_ExecuteSuccess, specmodel.Code_DataNoChange); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (w *ConfigFilesWatcher) Run() {
+	metrics := w.sdk.polarisClient.Metrics()
+	defer metrics.AddWatchedFiles(-len(w.watchFiles))
 	for {
 		select {
 		case <-w.sdk.ctx.Done():
 			return
 		default:
-			files := []WatchFile{}
-			for _, file := range w.watchFiles {
-				files = append(files, file)
-			}
-			resp, err := w.sdk.polarisClient.Resty().R().SetContext(w.sdk.ctx).SetBody(&WatchFilesRequest{files}).
-				Post(PolarisUrl("/config/v1/WatchConfigFile"))
+			configFileResp, err := w.longPoll()
 			if err != nil {
-				log.Fatalln(err)
-				return
-			}
-
-			configFileResp := ConfigFileResponse{}
-			err = json.Unmarshal(resp.Body(), &configFileResp)
-			if err != nil {
-				log.Fatalln(err)
-				return
+				if w.sdk.ctx.Err() != nil {
+					return
+				}
+				metrics.ObserveLongPoll(polaris.LongPollError)
+				log.Errorln(err)
+				// 网络错误、限流及服务端异常等待后重试，鉴权、参数错误等重试也无法恢复，通知错误监听器后停止监听
+				var polarisErr *polaris.PolarisError
+				if errors.As(err, &polarisErr) && !polaris.IsRetryable(err) {
+					w.fireError(err)
+					return
+				}
+				if !w.wait(watchRetryInterval) {
+					return
+				}
+				continue
 			}
-
 			// "/config/v1/WatchConfigFile" 接口在1分钟无更新时会返回 DataNoChange
 			if configFileResp.GetCode() == specmodel.Code_DataNoChange {
+				metrics.ObserveLongPoll(polaris.LongPollNoChange)
 				continue
 			}
-
-			// w.sdk.ctx Done
-			if configFileResp.GetCode() != specmodel.Code_ExecuteSuccess {
-				log.Errorln(configFileResp.GetCode())
-				return
-			}
+			metrics.ObserveLongPoll(polaris.LongPollChanged)
 
 			file := configFileResp.GetConfigFile()
-			newfileResp, err := w.sdk.GetConfigFile(file.GetNamespace(), file.GetFileGroup(), file.GetFileName())
+			if w.sdk.cache != nil {
+				w.sdk.cache.invalidate(cacheKey{file.GetNamespace(), file.GetFileGroup(), file.GetFileName()}, file.GetVersion())
+			}
+			newfileResp, err := w.sdk.GetConfigFileCtx(w.sdk.ctx, file.GetNamespace(), file.GetFileGroup(), file.GetFileName())
+			if err == nil && newfileResp.Stale {
+				// 北极星不可用时返回的是本地快照，不能记录新的版本号，等待后重新监听以再次获取
+				log.Warnf("[Config] refetch config file %s/%s/%s returned a stale snapshot, retry later",
+					file.GetNamespace(), file.GetFileGroup(), file.GetFileName())
+				if !w.wait(watchRetryInterval) {
+					return
+				}
+				continue
+			}
 
-			oldContent := w.watchFiles[configFileResp.GetConfigFile().GetFileName()].content
+			filename := file.GetFileName()
+			oldContent := w.watchFiles[filename].content
 			newContent := ""
+			metadata := file
 
-			if newfileResp.GetCode() == specmodel.Code_ExecuteSuccess {
+			switch {
+			case err == nil:
+				metadata = newfileResp.GetConfigFile()
 				newContent, err = newfileResp.GetConfigFile().GetContent()
 				if err != nil {
-					newContent = newfileResp.GetConfigFile().GetSourceContent()
+					// 解密失败时不能把密文当作内容通知给用户，更新版本号避免重复拉取，保留上一次的内容
+					log.Errorln(err)
+					w.fireError(err)
+					w.updateWatchFile(file, oldContent)
+					continue
 				}
-			}
-
-			if newfileResp.GetCode() == specmodel.Code_NotFoundResource {
+			case polaris.IsNotFound(err):
 				newContent = NotExistedFileContent
+			default:
+				// 未记录新的版本号，立即重新监听会马上返回并再次获取失败，等待后重试；
+				// 鉴权、参数错误等重试也无法恢复的错误通知错误监听器
+				log.Errorln(err)
+				var polarisErr *polaris.PolarisError
+				if errors.As(err, &polarisErr) && !polaris.IsRetryable(err) {
+					w.fireError(err)
+				}
+				if !w.wait(watchRetryInterval) {
+					return
+				}
+				continue
 			}
 
 			log.Infof("[Config] update content. filename=%v, file = %+v, old content = %s, new content = %s",
-				configFileResp.GetConfigFile().GetFileName(), configFileResp.GetConfigFile(), oldContent, newContent)
+				filename, file, oldContent, newContent)
 
 			var changeType model.ChangeType
-			w.watchFiles[configFileResp.GetConfigFile().GetFileName()].SetContent(newContent)
+			w.updateWatchFile(file, newContent)
 
 			if oldContent == NotExistedFileContent && newContent != NotExistedFileContent {
 				changeType = model.Added
@@ -145,56 +249,85 @@ func (w *ConfigFilesWatcher) Run() {
 			}
 
 			event := model.ConfigFileChangeEvent{
-				ConfigFileMetadata: newfileResp.GetConfigFile(),
+				ConfigFileMetadata: metadata,
 				OldValue:           oldContent,
 				NewValue:           newContent,
 				ChangeType:         changeType,
 			}
 
 			w.fireChangeEvent(event)
-			//log.Infoln("新消息:", configFileResp.GetCode(), configFileResp.GetCode(), configFileResp.GetConfigFile(), resp.String())
-			w.watchFiles[configFileResp.ConfigFile.GetFileName()] = WatchFile{
-				FileName:  configFileResp.ConfigFile.GetFileName(),
-				Namespace: configFileResp.ConfigFile.GetNamespace(),
-				Group:     configFileResp.ConfigFile.GetFileGroup(),
-				Version:   configFileResp.ConfigFile.GetVersion(),
-			}
 		}
 	}
 }
 
+// wait 等待 d 后返回 true，SDK 的 context 结束时返回 false
+func (w *ConfigFilesWatcher) wait(d time.Duration) bool {
+	select {
+	case <-w.sdk.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// updateWatchFile 记录配置文件最新的版本号及内容
+func (w *ConfigFilesWatcher) updateWatchFile(file *ConfigFile, content string) {
+	watchFile := WatchFile{
+		FileName:  file.GetFileName(),
+		Namespace: file.GetNamespace(),
+		Group:     file.GetFileGroup(),
+		Version:   file.GetVersion(),
+	}
+	watchFile.SetContent(content)
+	w.watchFiles[file.GetFileName()] = watchFile
+}
+
+// WatchConfigFiles 监听配置文件变更，监听任务在 SDK 的 context 结束后停止
 func (s *SDK) WatchConfigFiles(ns, group string, filenames ...string) (*ConfigFilesWatcher, error) {
+	return s.WatchConfigFilesCtx(context.Background(), ns, group, filenames...)
+}
+
+// WatchConfigFilesCtx 监听配置文件变更，ctx 只控制首次获取配置文件的请求，
+// 监听任务在 SDK 的 context 结束后停止。首次获取失败的文件按不存在监听，解密失败的文件内容按空处理，
+// 所有文件都获取或解密失败时返回错误，否则通过 AddErrorListener 回调这些错误，解密失败的错误为 *DecryptError
+func (s *SDK) WatchConfigFilesCtx(ctx context.Context, ns, group string, filenames ...string) (*ConfigFilesWatcher, error) {
 	if len(filenames) == 0 {
 		return nil, errors.New("least one file")
 	}
-	watchFiles := map[string]WatchFile{}
+	refs := make([]ConfigFileRef, 0, len(filenames))
 	for _, filename := range filenames {
 		if filename == "" {
 			log.Fatalln(filename)
 		}
-		configFile, err := s.GetConfigFile(ns, group, filename)
+		refs = append(refs, ConfigFileRef{Namespace: ns, Group: group, FileName: filename})
+	}
+
+	watchFiles := map[string]WatchFile{}
+	var fetchErrs []error
+	for _, result := range s.GetConfigFiles(ctx, refs...) {
+		filename, err := result.FileName, result.Err
 		if err != nil {
-			return nil, err
-		}
-		if configFile.GetCode() != specmodel.Code_ExecuteSuccess {
+			if !polaris.IsNotFound(err) {
+				// 获取失败的文件按不存在处理，北极星恢复或获得权限后通过监听获取并触发 Added 事件
+				log.Errorln(err)
+				fetchErrs = append(fetchErrs, err)
+			}
 			// 如果远程没有这个文件，设置 content 为 NotExistedFileContent
-			if configFile.GetCode() == specmodel.Code_NotFoundResource {
-				watchFiles[filename] = WatchFile{
-					FileName:  filename,
-					Group:     group,
-					Namespace: ns,
-					Version:   0,
-					content:   NotExistedFileContent,
-				}
-			} else {
-				log.Errorln(configFile.GetMessage())
+			watchFiles[filename] = WatchFile{
+				FileName:  filename,
+				Group:     group,
+				Namespace: ns,
+				Version:   0,
+				content:   NotExistedFileContent,
 			}
 			continue
 		}
+		configFile := result.Response
 		content, err := configFile.GetConfigFile().GetContent()
 		if err != nil {
+			// 解密失败时内容按空处理，不使用密文
 			log.Errorln(err)
-			content = configFile.GetConfigFile().GetSourceContent()
+			fetchErrs = append(fetchErrs, err)
 		}
 		watchFiles[filename] = WatchFile{
 			FileName:  filename,
@@ -205,10 +338,17 @@ func (s *SDK) WatchConfigFiles(ns, group string, filenames ...string) (*ConfigFi
 		}
 	}
 
+	// 所有文件都获取失败时返回错误
+	if len(fetchErrs) == len(refs) {
+		return nil, errors.Join(fetchErrs...)
+	}
+
 	watcher := &ConfigFilesWatcher{
 		sdk:        s,
 		watchFiles: watchFiles,
+		setupErrs:  fetchErrs,
 	}
+	s.polarisClient.Metrics().AddWatchedFiles(len(watchFiles))
 	go watcher.Run()
 	return watcher, nil
 }
@@ -0,0 +1,117 @@
+package sdk_test
+
+import (
+	"errors"
+	"github.com/nxsre/polaris-go"
+	"github.com/nxsre/polaris-go/crypto"
+	"github.com/nxsre/polaris-go/polaristest"
+	"github.com/nxsre/polaris-go/sdk"
+	polarismodel "github.com/polarismesh/polaris-go/pkg/model"
+	"github.com/polarismesh/specification/source/go/api/v1/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWatchRefetchFailure(t *testing.T) {
+	s := polaristest.NewServer(polaristest.WithWatchTimeout(time.Second))
+	defer s.Close()
+	client := newTestSDK(t, s)
+	s.Publish("ns", "g", "a.txt", "v1")
+
+	watcher, err := client.WatchConfigFiles("ns", "g", "a.txt")
+	if err != nil {
+		t.Fatalf("WatchConfigFiles() error = %v", err)
+	}
+	events := watcher.AddChangeListenerWithChannel()
+	errs := make(chan error, 16)
+	watcher.AddErrorListener(func(err error) { errs <- err })
+
+	// expectRetryLater 获取变更后的文件失败时不能立即重新监听，等待后重试
+	expectRetryLater := func() {
+		t.Helper()
+		watches, gets := s.Requests(polaristest.PathWatchConfigFile), s.Requests(polaristest.PathGetConfigFile)
+		time.Sleep(time.Second)
+		if got := s.Requests(polaristest.PathWatchConfigFile) - watches; got > 2 {
+			t.Errorf("watch requests in 1s after refetch failure = %d, want at most 2", got)
+		}
+		if got := s.Requests(polaristest.PathGetConfigFile) - gets; got > 2 {
+			t.Errorf("get requests in 1s after refetch failure = %d, want at most 2", got)
+		}
+		select {
+		case event := <-events:
+			t.Errorf("unexpected change event %+v", event)
+		default:
+		}
+	}
+
+	// 服务端异常，等待后重试，不通知错误监听器
+	s.InjectFault(polaristest.PathGetConfigFile, 0, polaristest.Fault{})
+	s.Publish("ns", "g", "a.txt", "v2")
+	expectRetryLater()
+	s.ClearFaults()
+	expectChange(t, events, "v1", "v2")
+	select {
+	case err := <-errs:
+		t.Errorf("error listener called with retryable error %v", err)
+	default:
+	}
+
+	// 无权限，通知错误监听器并等待后重试
+	s.InjectFault(polaristest.PathGetConfigFile, 0, polaristest.Fault{Code: model.Code_NotAllowedAccess, Status: http.StatusForbidden})
+	s.Publish("ns", "g", "a.txt", "v3")
+	select {
+	case err := <-errs:
+		var polarisErr *polaris.PolarisError
+		if !errors.As(err, &polarisErr) || polarisErr.Code != model.Code_NotAllowedAccess {
+			t.Errorf("error listener error = %v, want NotAllowedAccess", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("error listener not called")
+	}
+	expectRetryLater()
+	s.ClearFaults()
+	expectChange(t, events, "v2", "v3")
+}
+
+func TestWatchSetupDecryptError(t *testing.T) {
+	s := polaristest.NewServer()
+	defer s.Close()
+	client := newTestSDK(t, s)
+	s.Publish("ns", "g", "plain.txt", "v1")
+	s.Publish("ns", "g", "secret.txt", "ciphertext",
+		sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyUseEncrypted, Value: "true"},
+		sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyEncryptAlgo, Value: "unknown"},
+		sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyDataKey, Value: "AAAAAAAAAAAAAAAAAAAAAA=="},
+	)
+
+	// 所有文件都解密失败时返回错误
+	_, err := client.WatchConfigFiles("ns", "g", "secret.txt")
+	var decryptErr *sdk.DecryptError
+	if !errors.As(err, &decryptErr) || decryptErr.FileName != "secret.txt" || !errors.Is(err, crypto.ErrUnsupportedAlgo) {
+		t.Fatalf("WatchConfigFiles() error = %v, want *DecryptError of secret.txt", err)
+	}
+
+	// 部分文件解密失败时通过错误监听器回调
+	watcher, err := client.WatchConfigFiles("ns", "g", "plain.txt", "secret.txt")
+	if err != nil {
+		t.Fatalf("WatchConfigFiles() error = %v", err)
+	}
+	var errs []error
+	watcher.AddErrorListener(func(err error) { errs = append(errs, err) })
+	if len(errs) != 1 || !errors.As(errs[0], &decryptErr) || decryptErr.FileName != "secret.txt" {
+		t.Errorf("error listener errors = %v, want *DecryptError of secret.txt", errs)
+	}
+}
+
+func expectChange(t *testing.T, events <-chan polarismodel.ConfigFileChangeEvent, oldValue, newValue string) {
+	t.Helper()
+	select {
+	case event := <-events:
+		if event.ChangeType != polarismodel.Modified || event.OldValue != oldValue || event.NewValue != newValue {
+			t.Errorf("event = %v %q -> %q, want Modified %q -> %q", event.ChangeType, event.OldValue, event.NewValue, oldValue, newValue)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change event %q -> %q", oldValue, newValue)
+	}
+}
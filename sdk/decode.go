@@ -0,0 +1,288 @@
+package sdk
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/magiconair/properties"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v3"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 配置文件格式
+const (
+	FormatText       = "text"
+	FormatJSON       = "json"
+	FormatYAML       = "yaml"
+	FormatProperties = "properties"
+	FormatTOML       = "toml"
+	FormatXML        = "xml"
+	FormatINI        = "ini"
+)
+
+// extFormats 未设置格式时根据文件扩展名判断格式
+var extFormats = map[string]string{
+	".txt":        FormatText,
+	".json":       FormatJSON,
+	".yaml":       FormatYAML,
+	".yml":        FormatYAML,
+	".properties": FormatProperties,
+	".toml":       FormatTOML,
+	".xml":        FormatXML,
+	".ini":        FormatINI,
+}
+
+// 匹配 yaml、properties 错误信息中的行号
+var (
+	yamlLine       = regexp.MustCompile(`line (\d+)`)
+	propertiesLine = regexp.MustCompile(`Line (\d+)`)
+)
+
+// DecodeError 解析配置文件内容失败，Line、Column 从 1 开始，无法确定位置时为 0
+type DecodeError struct {
+	Namespace string
+	Group     string
+	FileName  string
+	Format    string
+	Line      int
+	Column    int
+	Err       error
+}
+
+func (e *DecodeError) Error() string {
+	pos := ""
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		pos = fmt.Sprintf(" at line %d, column %d", e.Line, e.Column)
+	case e.Line > 0:
+		pos = fmt.Sprintf(" at line %d", e.Line)
+	}
+	format := ""
+	if e.Format != "" {
+		format = e.Format + " "
+	}
+	return fmt.Sprintf("polaris: decode %sconfig file %s/%s/%s%s: %v", format, e.Namespace, e.Group, e.FileName, pos, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// GetFormat 获取配置文件格式，未设置时根据文件扩展名判断，无法判断时返回空字符串
+func (c *ConfigFile) GetFormat() string {
+	if c.Format != "" {
+		format := strings.ToLower(c.Format)
+		if format == "yml" {
+			return FormatYAML
+		}
+		return format
+	}
+	return extFormats[strings.ToLower(path.Ext(c.FileName))]
+}
+
+// Decode 按配置文件格式将解密后的内容解析到 v，解析失败时返回 *DecodeError。
+// json、yaml、toml、xml 格式按对应的库解析；properties 格式支持 *map[string]string、
+// *map[string]any 及带 properties tag 的结构体；ini 格式支持 *map[string]map[string]string、
+// *map[string]any 及带 ini tag 的结构体；text 格式只支持 *string
+func (c *ConfigFile) Decode(v any) error {
+	content, err := c.GetContent()
+	if err != nil {
+		return err
+	}
+
+	format := c.GetFormat()
+	switch format {
+	case FormatText:
+		err = decodeText(content, v)
+	case FormatJSON:
+		err = decodeJSON(content, v)
+	case FormatYAML:
+		err = decodeYAML(content, v)
+	case FormatProperties:
+		err = decodeProperties(content, v)
+	case FormatTOML:
+		err = decodeTOML(content, v)
+	case FormatXML:
+		err = decodeXML(content, v)
+	case FormatINI:
+		err = decodeINI(content, v)
+	default:
+		err = &DecodeError{Err: fmt.Errorf("unsupported format %q", format)}
+	}
+	if err == nil {
+		return nil
+	}
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		decodeErr = &DecodeError{Err: err}
+	}
+	decodeErr.Namespace, decodeErr.Group, decodeErr.FileName = c.Namespace, c.Group, c.FileName
+	decodeErr.Format = format
+	return decodeErr
+}
+
+// GetAs 获取配置文件并按格式解析为 T
+func GetAs[T any](s *SDK, ns, group, filename string) (T, error) {
+	return GetAsCtx[T](context.Background(), s, ns, group, filename)
+}
+
+// GetAsCtx 获取配置文件并按格式解析为 T，ctx 控制本次请求的超时及取消
+func GetAsCtx[T any](ctx context.Context, s *SDK, ns, group, filename string) (T, error) {
+	var v T
+	result, err := s.GetConfigFileCtx(ctx, ns, group, filename)
+	if err != nil {
+		return v, err
+	}
+	err = result.GetConfigFile().Decode(&v)
+	return v, err
+}
+
+func decodeText(content string, v any) error {
+	s, ok := v.(*string)
+	if !ok {
+		return fmt.Errorf("text format requires *string, got %T", v)
+	}
+	*s = content
+	return nil
+}
+
+// decodeJSON 使用标准库解析，以便从错误中获得出错位置
+func decodeJSON(content string, v any) error {
+	err := stdjson.Unmarshal([]byte(content), v)
+	var offset int64
+	var syntaxErr *stdjson.SyntaxError
+	var typeErr *stdjson.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &syntaxErr):
+		// Offset 为读取出错字符之后的偏移
+		offset = syntaxErr.Offset - 1
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+	line, column := position(content, int(offset))
+	return &DecodeError{Line: line, Column: column, Err: err}
+}
+
+func decodeYAML(content string, v any) error {
+	err := yaml.Unmarshal([]byte(content), v)
+	if err == nil {
+		return nil
+	}
+	line := 0
+	if match := yamlLine.FindStringSubmatch(err.Error()); match != nil {
+		line, _ = strconv.Atoi(match[1])
+	}
+	return &DecodeError{Line: line, Err: err}
+}
+
+func decodeTOML(content string, v any) error {
+	_, err := toml.Decode(content, v)
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		line, column := position(content, parseErr.Position.Start)
+		if parseErr.Position.Line > 0 {
+			line = parseErr.Position.Line
+		}
+		return &DecodeError{Line: line, Column: column, Err: err}
+	}
+	return err
+}
+
+func decodeXML(content string, v any) error {
+	err := xml.Unmarshal([]byte(content), v)
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &DecodeError{Line: syntaxErr.Line, Err: err}
+	}
+	return err
+}
+
+func decodeProperties(content string, v any) error {
+	p, err := properties.LoadString(content)
+	if err != nil {
+		line := 0
+		if match := propertiesLine.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+		}
+		return &DecodeError{Line: line, Err: err}
+	}
+	switch m := v.(type) {
+	case *map[string]string:
+		*m = p.Map()
+	case *map[string]any:
+		*m = map[string]any{}
+		for key, value := range p.Map() {
+			(*m)[key] = value
+		}
+	default:
+		return p.Decode(v)
+	}
+	return nil
+}
+
+func decodeINI(content string, v any) error {
+	f, err := ini.Load([]byte(content))
+	if err != nil {
+		var delimiterErr ini.ErrDelimiterNotFound
+		if errors.As(err, &delimiterErr) {
+			return &DecodeError{Line: lineOf(content, delimiterErr.Line), Err: err}
+		}
+		return err
+	}
+	switch m := v.(type) {
+	case *map[string]map[string]string:
+		*m = map[string]map[string]string{}
+		for _, section := range f.Sections() {
+			(*m)[section.Name()] = section.KeysHash()
+		}
+	case *map[string]any:
+		*m = map[string]any{}
+		for _, section := range f.Sections() {
+			keys := map[string]any{}
+			for key, value := range section.KeysHash() {
+				keys[key] = value
+			}
+			(*m)[section.Name()] = keys
+		}
+	default:
+		return f.MapTo(v)
+	}
+	return nil
+}
+
+// position 将字节偏移转换为行号及列号
+func position(content string, offset int) (line, column int) {
+	if offset > len(content) {
+		offset = len(content)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := content[:offset]
+	line = strings.Count(before, "\n") + 1
+	column = offset - strings.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// lineOf 查找内容所在的行号，找不到时返回 0
+func lineOf(content, text string) int {
+	text = strings.TrimSpace(text)
+	for i, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == text {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package sdk
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	type db struct {
+		Host string `json:"host" yaml:"host" toml:"host" xml:"host" properties:"host" ini:"host"`
+		Port int    `json:"port" yaml:"port" toml:"port" xml:"port" properties:"port" ini:"port"`
+	}
+	want := db{Host: "h1", Port: 3306}
+	files := []*ConfigFile{
+		{FileName: "db.json", Content: `{"host":"h1","port":3306}`},
+		{FileName: "db.yml", Content: "host: h1\nport: 3306\n"},
+		{FileName: "db.toml", Content: "host = \"h1\"\nport = 3306\n"},
+		{FileName: "db.xml", Content: "<db><host>h1</host><port>3306</port></db>"},
+		{FileName: "db.properties", Content: "host=h1\nport=3306\n"},
+		{FileName: "db.ini", Content: "host = h1\nport = 3306\n"},
+		{FileName: "db.conf", Format: "JSON", Content: `{"host":"h1","port":3306}`},
+	}
+	for _, file := range files {
+		var got db
+		if err := file.Decode(&got); err != nil || got != want {
+			t.Errorf("%s Decode() = %+v, %v, want %+v", file.FileName, got, err, want)
+		}
+	}
+
+	var text string
+	if err := (&ConfigFile{FileName: "a.txt", Content: "hello"}).Decode(&text); err != nil || text != "hello" {
+		t.Errorf("text Decode() = %q, %v, want hello", text, err)
+	}
+	var sections map[string]map[string]string
+	if err := (&ConfigFile{FileName: "a.ini", Content: "[db]\nhost = h1\n"}).Decode(&sections); err != nil ||
+		!reflect.DeepEqual(sections["db"], map[string]string{"host": "h1"}) {
+		t.Errorf("ini Decode() = %v, %v, want db section", sections, err)
+	}
+}
+
+func TestDecodeErrorPosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    *ConfigFile
+		v       any
+		line    int
+		column  int
+		wantMsg string
+	}{
+		{name: "json syntax", file: &ConfigFile{FileName: "a.json", Content: "{\n  \"host\": \"h1\",\n  \"port\": ,\n}"}, v: &map[string]any{}, line: 3, column: 11},
+		{name: "json type", file: &ConfigFile{FileName: "a.json", Content: "{\n  \"port\": \"x\"\n}"}, v: &struct{ Port int }{}, line: 2, column: 14},
+		{name: "yaml", file: &ConfigFile{FileName: "a.yaml", Content: "host: h1\nport: 1\nport: 2\n"}, v: &map[string]any{}, line: 3},
+		{name: "toml", file: &ConfigFile{FileName: "a.toml", Content: "host = \"h1\"\nport = = 1\n"}, v: &map[string]any{}, line: 2, column: 8},
+		{name: "xml", file: &ConfigFile{FileName: "a.xml", Content: "<db>\n<host>h1</host>\n</dbx>"}, v: &struct{}{}, line: 3},
+		{name: "properties", file: &ConfigFile{FileName: "a.properties", Content: "host=h1\nport=\\uZZZZ\n"}, v: &map[string]string{}, line: 2},
+		{name: "ini", file: &ConfigFile{FileName: "a.ini", Content: "host = h1\nbroken line\n"}, v: &map[string]any{}, line: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.file.Namespace, tt.file.Group = "ns", "g"
+			err := tt.file.Decode(tt.v)
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("Decode() error = %v, want *DecodeError", err)
+			}
+			if decodeErr.Line != tt.line || decodeErr.Column != tt.column {
+				t.Errorf("Decode() error at line %d column %d, want line %d column %d: %v",
+					decodeErr.Line, decodeErr.Column, tt.line, tt.column, err)
+			}
+			if decodeErr.Namespace != "ns" || decodeErr.Group != "g" || decodeErr.FileName != tt.file.FileName ||
+				decodeErr.Format != tt.file.GetFormat() || decodeErr.Err == nil {
+				t.Errorf("Decode() error = %+v, want file and format of %s", decodeErr, tt.file.FileName)
+			}
+		})
+	}
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	var v map[string]any
+	err := (&ConfigFile{FileName: "a.bin", Content: "x"}).Decode(&v)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.FileName != "a.bin" || decodeErr.Line != 0 {
+		t.Errorf("Decode() unknown format error = %v, want *DecodeError without position", err)
+	}
+	if err := (&ConfigFile{FileName: "a.txt", Content: "x"}).Decode(&v); !errors.As(err, &decodeErr) {
+		t.Errorf("text Decode() into map error = %v, want *DecodeError", err)
+	}
+}
@@ -0,0 +1,281 @@
+package sdk
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/magiconair/properties"
+	"github.com/nxsre/polaris-go/log"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parsedTree 解析后的配置文件内容，properties 格式为以完整 key 为键的 map
+type parsedTree struct {
+	version string
+	md5     string
+	root    any
+	flat    bool
+}
+
+// defaultTreeCacheSize 每个 SDK 缓存解析结果的配置文件数量上限
+const defaultTreeCacheSize = 256
+
+// treeCache 每个文件最新版本的解析结果，超过容量时淘汰最久未使用的文件。
+// ConfigFile 按值传递，缓存由获取配置文件的 SDK 持有，不同 SDK 访问的北极星集群互不影响
+type treeCache = lruCache[*parsedTree]
+
+// tree 获取解析后的内容，版本号及 MD5 未变化时使用缓存，没有版本号或不是 SDK 获取的配置文件时每次重新解析
+func (c *ConfigFile) tree() (*parsedTree, error) {
+	key := cacheKey{c.Namespace, c.Group, c.FileName}
+	cacheable := c.trees != nil && c.Version != ""
+	if cacheable {
+		if tree, ok := c.trees.get(key); ok && tree.version == c.Version && tree.md5 == c.Md5 {
+			return tree, nil
+		}
+	}
+
+	content, err := c.GetContent()
+	if err != nil {
+		return nil, err
+	}
+	tree := &parsedTree{version: c.Version, md5: c.Md5}
+	switch format := c.GetFormat(); format {
+	case FormatJSON:
+		decoder := stdjson.NewDecoder(strings.NewReader(content))
+		decoder.UseNumber()
+		err = decoder.Decode(&tree.root)
+	case FormatYAML:
+		err = yaml.Unmarshal([]byte(content), &tree.root)
+	case FormatTOML:
+		var root map[string]any
+		_, err = toml.Decode(content, &root)
+		tree.root = root
+	case FormatProperties:
+		var p *properties.Properties
+		if p, err = properties.LoadString(content); err == nil {
+			flat := map[string]any{}
+			for key, value := range p.Map() {
+				flat[key] = value
+			}
+			tree.root, tree.flat = flat, true
+		}
+	default:
+		err = fmt.Errorf("path lookup does not support format %q", format)
+	}
+	if err != nil {
+		return nil, &DecodeError{Namespace: c.Namespace, Group: c.Group, FileName: c.FileName, Format: c.GetFormat(), Err: err}
+	}
+
+	if cacheable {
+		c.trees.set(key, tree)
+	}
+	return tree, nil
+}
+
+// Get 按路径获取配置项，路径使用 . 分隔 key，使用 [n] 访问数组元素，如 db.replicas[0].host。
+// 支持 json、yaml、toml、properties 格式，properties 格式按完整 key 查找。
+// 返回的 map、slice 为缓存的解析结果，不能修改
+func (c *ConfigFile) Get(path string) (any, bool) {
+	tree, err := c.tree()
+	if err != nil {
+		log.Errorln(err)
+		return nil, false
+	}
+	if tree.flat {
+		value, ok := tree.root.(map[string]any)[path]
+		return value, ok
+	}
+
+	segments, err := parsePath(path)
+	if err != nil {
+		log.Errorf("invalid config path %q: %v", path, err)
+		return nil, false
+	}
+	value, ok := tree.root, true
+	for _, segment := range segments {
+		switch node := value.(type) {
+		case map[string]any:
+			if segment.index >= 0 {
+				return nil, false
+			}
+			if value, ok = node[segment.key]; !ok {
+				return nil, false
+			}
+		case []any:
+			if segment.index < 0 || segment.index >= len(node) {
+				return nil, false
+			}
+			value = node[segment.index]
+		case []map[string]any:
+			// toml 的表数组
+			if segment.index < 0 || segment.index >= len(node) {
+				return nil, false
+			}
+			value = node[segment.index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// GetString 获取字符串配置项，不存在或不是标量时返回 def
+func (c *ConfigFile) GetString(path, def string) string {
+	value, ok := c.Get(path)
+	if !ok {
+		return def
+	}
+	if s, ok := toString(value); ok {
+		return s
+	}
+	return def
+}
+
+// GetInt 获取整数配置项，不存在或无法转换为整数时返回 def
+func (c *ConfigFile) GetInt(path string, def int) int {
+	value, ok := c.Get(path)
+	if !ok {
+		return def
+	}
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	case float64:
+		if v == float64(int(v)) {
+			return int(v)
+		}
+	case stdjson.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i)
+		}
+		if f, err := v.Float64(); err == nil && f == float64(int(f)) {
+			return int(f)
+		}
+	case string:
+		if i, err := strconv.Atoi(v); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+// GetBool 获取布尔配置项，不存在或无法转换为布尔值时返回 def
+func (c *ConfigFile) GetBool(path string, def bool) bool {
+	value, ok := c.Get(path)
+	if !ok {
+		return def
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// GetDuration 获取时长配置项，字符串按 time.ParseDuration 解析，如 1m30s，整数按纳秒处理，
+// 不存在或无法解析时返回 def
+func (c *ConfigFile) GetDuration(path string, def time.Duration) time.Duration {
+	value, ok := c.Get(path)
+	if !ok {
+		return def
+	}
+	switch v := value.(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	case int, int64, stdjson.Number:
+		if i, err := strconv.ParseInt(fmt.Sprint(v), 10, 64); err == nil {
+			return time.Duration(i)
+		}
+	}
+	return def
+}
+
+// GetStringSlice 获取字符串数组配置项，字符串按逗号分隔，不存在或元素不是标量时返回 def
+func (c *ConfigFile) GetStringSlice(path string, def []string) []string {
+	value, ok := c.Get(path)
+	if !ok {
+		return def
+	}
+	switch v := value.(type) {
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := toString(item)
+			if !ok {
+				return def
+			}
+			result = append(result, s)
+		}
+		return result
+	case string:
+		if v == "" {
+			return []string{}
+		}
+		result := strings.Split(v, ",")
+		for i := range result {
+			result[i] = strings.TrimSpace(result[i])
+		}
+		return result
+	}
+	return def
+}
+
+// pathSegment 路径中的一段，index 小于 0 时为 map 的 key
+type pathSegment struct {
+	key   string
+	index int
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key = part[:i]
+		}
+		if key != "" {
+			segments = append(segments, pathSegment{key: key, index: -1})
+		} else if part == "" || !strings.HasPrefix(part, "[") {
+			return nil, fmt.Errorf("empty key")
+		}
+
+		rest := part[len(key):]
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("malformed index %q", rest)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("malformed index %q", rest[:end+1])
+			}
+			segments = append(segments, pathSegment{index: index})
+			rest = rest[end+1:]
+		}
+	}
+	return segments, nil
+}
+
+func toString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case map[string]any, []any, []map[string]any, nil:
+		return "", false
+	default:
+		return fmt.Sprint(v), true
+	}
+}
@@ -0,0 +1,135 @@
+package sdk
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []pathSegment
+		wantErr bool
+	}{
+		{path: "db", want: []pathSegment{{key: "db", index: -1}}},
+		{path: "db.host", want: []pathSegment{{key: "db", index: -1}, {key: "host", index: -1}}},
+		{path: "db.replicas[1].host", want: []pathSegment{{key: "db", index: -1}, {key: "replicas", index: -1}, {index: 1}, {key: "host", index: -1}}},
+		{path: "matrix[0][2]", want: []pathSegment{{key: "matrix", index: -1}, {index: 0}, {index: 2}}},
+		{path: "[3]", want: []pathSegment{{index: 3}}},
+		{path: "", wantErr: true},
+		{path: "db..host", wantErr: true},
+		{path: "db[", wantErr: true},
+		{path: "db[x]", wantErr: true},
+		{path: "db[-1]", wantErr: true},
+		{path: "db[0]x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePath(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestConfigFileGet(t *testing.T) {
+	files := []*ConfigFile{
+		{FileName: "app.json", Content: `{"db":{"host":"h1","port":3306,"debug":true,"timeout":"1m30s","replicas":[{"host":"r1"},{"host":"r2"}],"tags":["a","b"]}}`},
+		{FileName: "app.yaml", Content: "db:\n  host: h1\n  port: 3306\n  debug: true\n  timeout: 1m30s\n  replicas:\n    - host: r1\n    - host: r2\n  tags: [a, b]\n"},
+		{FileName: "app.toml", Content: "[db]\nhost = \"h1\"\nport = 3306\ndebug = true\ntimeout = \"1m30s\"\ntags = [\"a\", \"b\"]\n[[db.replicas]]\nhost = \"r1\"\n[[db.replicas]]\nhost = \"r2\"\n"},
+	}
+	for _, file := range files {
+		t.Run(file.GetFormat(), func(t *testing.T) {
+			if got := file.GetString("db.host", ""); got != "h1" {
+				t.Errorf("GetString(db.host) = %q, want h1", got)
+			}
+			if got := file.GetString("db.replicas[1].host", ""); got != "r2" {
+				t.Errorf("GetString(db.replicas[1].host) = %q, want r2", got)
+			}
+			if got := file.GetInt("db.port", 0); got != 3306 {
+				t.Errorf("GetInt(db.port) = %d, want 3306", got)
+			}
+			if got := file.GetBool("db.debug", false); !got {
+				t.Error("GetBool(db.debug) = false, want true")
+			}
+			if got := file.GetDuration("db.timeout", 0); got != 90*time.Second {
+				t.Errorf("GetDuration(db.timeout) = %v, want 1m30s", got)
+			}
+			if got := file.GetStringSlice("db.tags", nil); !reflect.DeepEqual(got, []string{"a", "b"}) {
+				t.Errorf("GetStringSlice(db.tags) = %v, want [a b]", got)
+			}
+
+			// 不存在、越界、类型不匹配时返回默认值
+			if got := file.GetString("db.missing", "def"); got != "def" {
+				t.Errorf("GetString(db.missing) = %q, want def", got)
+			}
+			if got := file.GetString("db.replicas[2].host", "def"); got != "def" {
+				t.Errorf("GetString(db.replicas[2].host) = %q, want def", got)
+			}
+			if got := file.GetString("db.replicas", "def"); got != "def" {
+				t.Errorf("GetString(db.replicas) = %q, want def", got)
+			}
+			if got := file.GetInt("db.host", -1); got != -1 {
+				t.Errorf("GetInt(db.host) = %d, want -1", got)
+			}
+			if _, ok := file.Get("db.host[0]"); ok {
+				t.Error("Get(db.host[0]) found, want not found")
+			}
+		})
+	}
+}
+
+func TestConfigFileGetProperties(t *testing.T) {
+	file := &ConfigFile{FileName: "app.properties", Content: "db.host=h1\ndb.port=3306\ndb.debug=true\ndb.tags=a, b\n"}
+	if got := file.GetString("db.host", ""); got != "h1" {
+		t.Errorf("GetString(db.host) = %q, want h1", got)
+	}
+	if got := file.GetInt("db.port", 0); got != 3306 {
+		t.Errorf("GetInt(db.port) = %d, want 3306", got)
+	}
+	if got := file.GetBool("db.debug", false); !got {
+		t.Error("GetBool(db.debug) = false, want true")
+	}
+	if got := file.GetStringSlice("db.tags", nil); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("GetStringSlice(db.tags) = %v, want [a b]", got)
+	}
+	if _, ok := file.Get("db"); ok {
+		t.Error("Get(db) found, want not found")
+	}
+}
+
+func TestConfigFileTreeCache(t *testing.T) {
+	trees := newLRUCache[*parsedTree](1)
+	a := &ConfigFile{Namespace: "ns", Group: "g", FileName: "a.json", Version: "1", Md5: "m1", Content: `{"k":"v1"}`, trees: trees}
+	if got := a.GetString("k", ""); got != "v1" {
+		t.Fatalf("GetString(k) = %q, want v1", got)
+	}
+	if _, ok := trees.get(cacheKey{"ns", "g", "a.json"}); !ok {
+		t.Fatal("parsed tree not cached")
+	}
+
+	// 版本号变化后重新解析
+	a2 := *a
+	a2.Version, a2.Md5, a2.Content = "2", "m2", `{"k":"v2"}`
+	if got := a2.GetString("k", ""); got != "v2" {
+		t.Errorf("GetString(k) of version 2 = %q, want v2", got)
+	}
+
+	// 超过容量时淘汰最久未使用的文件
+	b := &ConfigFile{Namespace: "ns", Group: "g", FileName: "b.json", Version: "1", Content: `{"k":"b"}`, trees: trees}
+	b.GetString("k", "")
+	if _, ok := trees.get(cacheKey{"ns", "g", "a.json"}); ok {
+		t.Error("a.json still cached after exceeding cache size")
+	}
+
+	// 没有版本号时不缓存
+	c := &ConfigFile{Namespace: "ns", Group: "g", FileName: "c.json", Content: `{"k":"c"}`, trees: trees}
+	c.GetString("k", "")
+	if _, ok := trees.get(cacheKey{"ns", "g", "c.json"}); ok {
+		t.Error("file without version cached")
+	}
+}
@@ -0,0 +1,51 @@
+package sdk
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache 按 命名空间/分组/文件名 缓存每个文件的最新结果，超过容量时淘汰最久未使用的文件
+type lruCache[V any] struct {
+	lock  sync.Mutex
+	size  int
+	files map[cacheKey]*list.Element
+	lru   *list.List
+}
+
+type lruEntry[V any] struct {
+	key   cacheKey
+	value V
+}
+
+func newLRUCache[V any](size int) *lruCache[V] {
+	return &lruCache[V]{size: size, files: map[cacheKey]*list.Element{}, lru: list.New()}
+}
+
+func (c *lruCache[V]) get(key cacheKey) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.files[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*lruEntry[V]).value, true
+}
+
+func (c *lruCache[V]) set(key cacheKey, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.files[key]; ok {
+		elem.Value.(*lruEntry[V]).value = value
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.files[key] = c.lru.PushFront(&lruEntry[V]{key: key, value: value})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.files, oldest.Value.(*lruEntry[V]).key)
+	}
+}
@@ -0,0 +1,41 @@
+package sdk
+
+import (
+	"crypto/rsa"
+	"time"
+)
+
+// Option 创建 SDK 的可选配置
+type Option func(*SDK)
+
+// WithSnapshotDir 将成功获取的配置文件保存到 dir 目录下，
+// 北极星不可用时读取配置文件会返回本地快照，并在响应中标记为 Stale
+func WithSnapshotDir(dir string) Option {
+	return func(s *SDK) {
+		s.snapshots = &snapshotStore{dir: dir}
+	}
+}
+
+// WithCache 开启配置文件读缓存，并发读取同一文件时只访问一次北极星。
+// 缓存在 ttl 到期或监听到新版本时失效，ttl 小于等于 0 时只在监听到新版本时失效
+func WithCache(ttl time.Duration) Option {
+	return func(s *SDK) {
+		s.cache = newConfigCache(ttl)
+	}
+}
+
+// WithFetchConcurrency 设置 GetConfigFiles 批量获取配置文件的并发数，默认为 8
+func WithFetchConcurrency(n int) Option {
+	return func(s *SDK) {
+		s.fetchConcurrency = n
+	}
+}
+
+// WithRSAKey 使用指定的 RSA 密钥对保护数据密钥，默认在首次获取配置文件时生成。
+// 开启 WithSnapshotDir 时，加密配置文件的快照保存的是公钥加密的数据密钥，未指定密钥对时
+// 生成的密钥对以 0600 权限保存在快照目录下的 .rsa_key.pem，进程重启后继续使用
+func WithRSAKey(key *rsa.PrivateKey) Option {
+	return func(s *SDK) {
+		s.rsaKey = &rsaKey{key: key}
+	}
+}
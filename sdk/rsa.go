@@ -0,0 +1,65 @@
+package sdk
+
+import (
+	"crypto/rsa"
+	"errors"
+	"github.com/nxsre/polaris-go/crypto"
+	"github.com/nxsre/polaris-go/internal/fileutil"
+	"github.com/nxsre/polaris-go/log"
+	"os"
+	"sync"
+)
+
+// rsaKeyFile 开启快照时保存密钥对的文件名，位于快照目录下
+const rsaKeyFile = ".rsa_key.pem"
+
+// rsaKey 获取配置文件时发送公钥，北极星使用公钥加密返回的数据密钥，
+// 数据密钥只在本地使用私钥解密，泄露到日志或代理中的密文数据密钥无法直接使用
+type rsaKey struct {
+	once      sync.Once
+	key       *rsa.PrivateKey
+	publicKey string
+	err       error
+	// path 不为空时从该文件加载密钥对，文件不存在时将生成的密钥对保存到该文件，
+	// 使进程重启后仍能解密快照中公钥加密的数据密钥
+	path string
+}
+
+// get 获取密钥对，未通过 WithRSAKey 指定时在首次使用时加载或生成
+func (k *rsaKey) get() (*rsa.PrivateKey, string, error) {
+	k.once.Do(func() {
+		if k.key == nil && k.path != "" {
+			k.key = loadRSAKey(k.path)
+		}
+		if k.key == nil {
+			k.key, k.err = crypto.GenerateRSAKey(crypto.DefaultRSAKeyBits)
+			if k.err != nil {
+				return
+			}
+			if k.path != "" {
+				if err := fileutil.WriteFileAtomic(k.path, crypto.EncodeRSAPrivateKey(k.key)); err != nil {
+					log.Errorf("save rsa key to %s: %v", k.path, err)
+				}
+			}
+		}
+		k.publicKey = crypto.EncodeRSAPublicKey(&k.key.PublicKey)
+	})
+	return k.key, k.publicKey, k.err
+}
+
+// loadRSAKey 加载保存的密钥对，文件不存在或无法解析时返回 nil
+func loadRSAKey(path string) *rsa.PrivateKey {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Errorf("load rsa key from %s: %v", path, err)
+		}
+		return nil
+	}
+	key, err := crypto.ParseRSAPrivateKey(data)
+	if err != nil {
+		log.Errorf("load rsa key from %s: %v", path, err)
+		return nil
+	}
+	return key
+}
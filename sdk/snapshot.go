@@ -0,0 +1,134 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"github.com/nxsre/polaris-go"
+	"github.com/nxsre/polaris-go/internal/fileutil"
+	"github.com/polarismesh/specification/source/go/api/v1/model"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// snapshot 保存在本地的配置文件快照
+type snapshot struct {
+	SavedAt    time.Time   `json:"saved_at"`
+	ConfigFile *ConfigFile `json:"config_file"`
+}
+
+// snapshotStore 按 命名空间/分组/文件名 保存配置文件快照，文件内容与北极星返回的一致，加密文件保存密文
+type snapshotStore struct {
+	dir string
+
+	// saved 记录已保存快照的 版本号/MD5，内容未变化时不重复写入
+	lock  sync.Mutex
+	saved map[cacheKey]string
+}
+
+// snapshotName 转义路径中的特殊字符，避免文件名中的 / 或 .. 访问快照目录之外的文件
+func snapshotName(name string) string {
+	escaped := url.PathEscape(name)
+	if escaped == "." || escaped == ".." {
+		escaped = strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	return escaped
+}
+
+func (s *snapshotStore) path(ns, group, filename string) string {
+	return filepath.Join(s.dir, snapshotName(ns), snapshotName(group), snapshotName(filename)+".json")
+}
+
+// save 原子地写入快照文件，版本号及 MD5 未变化时跳过
+func (s *snapshotStore) save(file *ConfigFile) error {
+	if file == nil {
+		return errors.New("polaris: empty config file")
+	}
+	key := cacheKey{file.GetNamespace(), file.GetFileGroup(), file.GetFileName()}
+	revision := file.Version + "/" + file.GetMd5()
+	// 没有版本号时无法判断内容是否变化
+	if file.Version != "" && s.savedRevision(key) == revision {
+		return nil
+	}
+	data, err := json.Marshal(&snapshot{SavedAt: time.Now(), ConfigFile: file})
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteFileAtomic(s.path(key.namespace, key.group, key.fileName), data); err != nil {
+		return err
+	}
+	s.setSavedRevision(key, revision)
+	return nil
+}
+
+// savedRevision 获取已保存快照的 版本号/MD5，进程启动后首次保存时从快照文件读取
+func (s *snapshotStore) savedRevision(key cacheKey) string {
+	s.lock.Lock()
+	revision, ok := s.saved[key]
+	s.lock.Unlock()
+	if ok {
+		return revision
+	}
+	snapshot, err := s.load(key.namespace, key.group, key.fileName)
+	if err != nil {
+		return ""
+	}
+	revision = snapshot.ConfigFile.Version + "/" + snapshot.ConfigFile.GetMd5()
+	s.setSavedRevision(key, revision)
+	return revision
+}
+
+func (s *snapshotStore) setSavedRevision(key cacheKey, revision string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.saved == nil {
+		s.saved = map[cacheKey]string{}
+	}
+	if revision == "" {
+		delete(s.saved, key)
+		return
+	}
+	s.saved[key] = revision
+}
+
+func (s *snapshotStore) load(ns, group, filename string) (*ConfigFileResponse, error) {
+	data, err := os.ReadFile(s.path(ns, group, filename))
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, err
+	}
+	if snap.ConfigFile == nil {
+		return nil, errors.New("polaris: empty config file snapshot")
+	}
+	return &ConfigFileResponse{
+		Code:         model.Code_ExecuteSuccess,
+		Info:         "stale snapshot",
+		ConfigFile:   snap.ConfigFile,
+		Stale:        true,
+		SnapshotTime: snap.SavedAt,
+	}, nil
+}
+
+func (s *snapshotStore) remove(ns, group, filename string) error {
+	s.setSavedRevision(cacheKey{ns, group, filename}, "")
+	err := os.Remove(s.path(ns, group, filename))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// isUnavailable 判断是否为北极星不可用导致的错误，调用方主动取消的请求不使用快照
+func isUnavailable(ctx context.Context, err error) bool {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return false
+	}
+	var polarisErr *polaris.PolarisError
+	return !errors.As(err, &polarisErr) || polaris.IsRetryable(err)
+}
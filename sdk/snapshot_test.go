@@ -0,0 +1,127 @@
+package sdk_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/nxsre/polaris-go"
+	"github.com/nxsre/polaris-go/crypto"
+	"github.com/nxsre/polaris-go/polaristest"
+	"github.com/nxsre/polaris-go/sdk"
+	"github.com/polarismesh/specification/source/go/api/v1/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotFallback(t *testing.T) {
+	dir := t.TempDir()
+	s := polaristest.NewServer()
+	defer s.Close()
+	client := newTestSDK(t, s, sdk.WithSnapshotDir(dir))
+	s.Publish("ns", "g", "a.txt", "v1")
+
+	result, err := client.GetConfigFile("ns", "g", "a.txt")
+	if err != nil || result.Stale {
+		t.Fatalf("GetConfigFile() = stale %v, %v, want fresh", result.Stale, err)
+	}
+
+	// 北极星返回无法重试的错误时不使用快照
+	s.InjectFault(polaristest.PathGetConfigFile, 1, polaristest.Fault{Code: model.Code_NotAllowedAccess, Status: http.StatusForbidden})
+	if _, err := client.GetConfigFile("ns", "g", "a.txt"); !polaris.IsUnauthorized(err) {
+		t.Errorf("GetConfigFile() with forbidden fault error = %v, want unauthorized", err)
+	}
+
+	s.Close()
+	result, err = client.GetConfigFile("ns", "g", "a.txt")
+	if err != nil {
+		t.Fatalf("GetConfigFile() after server closed error = %v", err)
+	}
+	if !result.Stale || result.SnapshotTime.IsZero() || result.GetConfigFile().GetSourceContent() != "v1" {
+		t.Errorf("GetConfigFile() after server closed = stale %v at %v content %q, want stale snapshot of v1",
+			result.Stale, result.SnapshotTime, result.GetConfigFile().GetSourceContent())
+	}
+	if _, err := client.GetConfigFile("ns", "g", "b.txt"); err == nil {
+		t.Error("GetConfigFile() without snapshot after server closed error = nil")
+	}
+}
+
+func TestSnapshotSave(t *testing.T) {
+	dir := t.TempDir()
+	s := polaristest.NewServer()
+	defer s.Close()
+	client := newTestSDK(t, s, sdk.WithSnapshotDir(dir))
+	path := filepath.Join(dir, "ns", "g", "a.txt.json")
+	modTime := func() time.Time {
+		t.Helper()
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat snapshot: %v", err)
+		}
+		return info.ModTime()
+	}
+
+	s.Publish("ns", "g", "a.txt", "v1")
+	if _, err := client.GetConfigFile("ns", "g", "a.txt"); err != nil {
+		t.Fatalf("GetConfigFile() error = %v", err)
+	}
+	saved := modTime()
+
+	// 版本未变化时不重写快照
+	time.Sleep(20 * time.Millisecond)
+	if _, err := client.GetConfigFile("ns", "g", "a.txt"); err != nil {
+		t.Fatalf("GetConfigFile() error = %v", err)
+	}
+	if !modTime().Equal(saved) {
+		t.Error("snapshot rewritten for unchanged version")
+	}
+
+	s.Publish("ns", "g", "a.txt", "v2")
+	if _, err := client.GetConfigFile("ns", "g", "a.txt"); err != nil {
+		t.Fatalf("GetConfigFile() error = %v", err)
+	}
+	if modTime().Equal(saved) {
+		t.Error("snapshot not rewritten for new version")
+	}
+
+	// 文件删除后移除快照
+	s.Delete("ns", "g", "a.txt")
+	if _, err := client.GetConfigFile("ns", "g", "a.txt"); !polaris.IsNotFound(err) {
+		t.Fatalf("GetConfigFile() deleted file error = %v, want not found", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stat snapshot of deleted file error = %v, want not exist", err)
+	}
+}
+
+// 快照中的数据密钥由 SDK 的公钥加密，重启后的 SDK 需要能够解密
+func TestSnapshotEncryptedAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	s := polaristest.NewServer()
+	defer s.Close()
+	cryptor, _ := crypto.GetCryptor(crypto.AlgoAESGCM)
+	key, _ := cryptor.GenerateKey()
+	ciphertext, err := cryptor.Encrypt("secret", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Publish("ns", "g", "a.txt", ciphertext,
+		sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyUseEncrypted, Value: "true"},
+		sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyEncryptAlgo, Value: crypto.AlgoAESGCM},
+		sdk.ConfigFileTag{Key: sdk.ConfigFileTagKeyDataKey, Value: base64.StdEncoding.EncodeToString(key)},
+	)
+	if _, err := newTestSDK(t, s, sdk.WithSnapshotDir(dir)).GetConfigFile("ns", "g", "a.txt"); err != nil {
+		t.Fatalf("GetConfigFile() error = %v", err)
+	}
+
+	restarted := newTestSDK(t, s, sdk.WithSnapshotDir(dir))
+	s.Close()
+	result, err := restarted.GetConfigFile("ns", "g", "a.txt")
+	if err != nil || !result.Stale {
+		t.Fatalf("GetConfigFile() after restart = %+v, %v, want stale snapshot", result, err)
+	}
+	if content, err := result.GetConfigFile().GetContent(); err != nil || content != "secret" {
+		t.Errorf("GetContent() of snapshot after restart = %q, %v, want %q", content, err, "secret")
+	}
+}
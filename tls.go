@@ -0,0 +1,160 @@
+package polaris
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/nxsre/polaris-go/log"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultCertReloadInterval = 10 * time.Second
+
+// TLSConfig 访问 https 北极星节点的 TLS 配置，证书文件变更后会自动重新加载
+type TLSConfig struct {
+	// CAFile 校验服务端证书的 CA 证书，为空时使用系统 CA
+	CAFile string
+	// CertFile、KeyFile 双向认证使用的客户端证书和私钥
+	CertFile string
+	KeyFile  string
+	// ServerName 覆盖校验服务端证书时使用的主机名，默认使用节点地址中的主机名
+	ServerName         string
+	InsecureSkipVerify bool
+	// ReloadInterval 检查证书文件是否变更的间隔，默认 10s
+	ReloadInterval time.Duration
+}
+
+func (c *TLSConfig) build() (*tls.Config, error) {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, errors.New("polaris: tls cert file and key file must be set together")
+	}
+	interval := c.ReloadInterval
+	if interval <= 0 {
+		interval = defaultCertReloadInterval
+	}
+	r := &certReloader{config: c, interval: interval}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CertFile != "" {
+		tlsConfig.GetClientCertificate = r.clientCertificate
+	}
+	if c.CAFile != "" && !c.InsecureSkipVerify {
+		// 使用自定义校验以便 CA 证书能够热更新
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = r.verifyConnection
+	}
+	return tlsConfig, nil
+}
+
+// certReloader 缓存证书，并按间隔检查文件修改时间，变更后重新加载
+type certReloader struct {
+	config   *TLSConfig
+	interval time.Duration
+
+	lock      sync.RWMutex
+	loaded    bool
+	checkedAt time.Time
+	modTime   time.Time
+	cert      *tls.Certificate
+	roots     *x509.CertPool
+}
+
+func (r *certReloader) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) verifyConnection(cs tls.ConnectionState) error {
+	r.maybeReload()
+	r.lock.RLock()
+	roots := r.roots
+	r.lock.RUnlock()
+
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("polaris: server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func (r *certReloader) maybeReload() {
+	r.lock.RLock()
+	due := time.Since(r.checkedAt) >= r.interval
+	r.lock.RUnlock()
+	if !due {
+		return
+	}
+	if err := r.reload(); err != nil {
+		// 加载失败时继续使用旧证书
+		log.Errorln("reload polaris tls certificates:", err)
+	}
+}
+
+func (r *certReloader) reload() error {
+	modTime := r.latestModTime()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.checkedAt = time.Now()
+	if r.loaded && !modTime.After(r.modTime) {
+		return nil
+	}
+
+	var cert *tls.Certificate
+	if r.config.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+	var roots *x509.CertPool
+	if r.config.CAFile != "" {
+		pem, err := os.ReadFile(r.config.CAFile)
+		if err != nil {
+			return err
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("polaris: no certificates found in %s", r.config.CAFile)
+		}
+	}
+
+	if r.loaded {
+		log.Infoln("polaris tls certificates reloaded")
+	}
+	r.cert, r.roots, r.modTime, r.loaded = cert, roots, modTime, true
+	return nil
+}
+
+func (r *certReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, file := range []string{r.config.CAFile, r.config.CertFile, r.config.KeyFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
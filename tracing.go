@@ -0,0 +1,79 @@
+package polaris
+
+import (
+	"context"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/nxsre/polaris-go"
+
+// span 属性
+const (
+	AttrNamespace = attribute.Key("polaris.namespace")
+	AttrGroup     = attribute.Key("polaris.group")
+	AttrFileName  = attribute.Key("polaris.file_name")
+	AttrCode      = attribute.Key("polaris.code")
+	AttrNode      = attribute.Key("polaris.node")
+)
+
+// ConfigFileAttributes 配置文件的 span 属性
+func ConfigFileAttributes(ns, group, fileName string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttrNamespace.String(ns),
+		AttrGroup.String(group),
+		AttrFileName.String(fileName),
+	}
+}
+
+// StartSpan 开始一个 SDK 调用的 span，未开启 tracing 时为 noop span
+func (p *Polaris) StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return p.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// EndSpan 记录北极星返回码、响应节点及错误后结束 span，result 仅在 err 为空时使用
+func EndSpan(span trace.Span, resp *resty.Response, result CodeResult, err error) {
+	defer span.End()
+
+	var polarisErr *PolarisError
+	switch {
+	case errors.As(err, &polarisErr):
+		span.SetAttributes(AttrCode.Int64(int64(polarisErr.Code)))
+	case err == nil && result != nil:
+		span.SetAttributes(AttrCode.Int64(int64(result.GetCode())))
+	}
+	if node := Node(resp); node != "" {
+		span.SetAttributes(AttrNode.String(node))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// injectTraceContext 将 trace context 注入请求头
+func injectTraceContext(propagator propagation.TextMapPropagator) resty.RequestMiddleware {
+	return func(_ *resty.Client, r *resty.Request) error {
+		propagator.Inject(r.Context(), propagation.HeaderCarrier(r.Header))
+		return nil
+	}
+}
+
+func newTracer(o *options) trace.Tracer {
+	if o.tracerProvider == nil {
+		return trace.NewNoopTracerProvider().Tracer(tracerName)
+	}
+	return o.tracerProvider.Tracer(tracerName)
+}
+
+func propagator(o *options) propagation.TextMapPropagator {
+	if o.propagator != nil {
+		return o.propagator
+	}
+	return otel.GetTextMapPropagator()
+}